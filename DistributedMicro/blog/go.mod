@@ -2,4 +2,4 @@ module github.com/mszlu521/blog
 
 go 1.18
 
-require github.com/go-sql-driver/mysql v1.6.0 // indirect
+require github.com/go-sql-driver/mysql v1.6.0
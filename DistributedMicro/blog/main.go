@@ -52,6 +52,7 @@ func main() {
 	//}
 	//auth.Users["mszlu"] = "123456"
 	//engine.Use(auth.BasicAuth)
+	engine.Use(msgo.RequestID)
 	jh := &token.JwtHandler{Key: []byte("123456")}
 	//为特定的中间件 需要指定不进行拦截的请求
 	engine.Use(jh.AuthInterceptor)
@@ -173,12 +174,14 @@ func main() {
 		//if err != nil {
 		//	logger.Println(err)
 		//}
-		files := ctx.FormFiles("file")
+		files, _ := ctx.FormFiles("file")
 		for _, file := range files {
-			ctx.SaveUploadedFile(file, "./upload/"+file.Filename)
+			if _, err := ctx.SaveFile(file); err != nil {
+				log.Println(err)
+			}
 		}
 		ctx.JSON(http.StatusOK, m)
-	})
+	}, msgo.Upload(msgo.UploadPolicy{MaxFileSize: 10 << 20, Storage: &msgo.DiskStorage{Dir: "./upload"}}))
 	//g.Post("/file", func(ctx *msgo.Context) {
 	//	m, _ := ctx.GetPostFormMap("user")
 	//
@@ -5,12 +5,14 @@ import (
 	"github.com/mszlu521/goodscenter/model"
 	"github.com/mszlu521/msgo"
 	"github.com/mszlu521/msgo/breaker"
+	"github.com/mszlu521/msgo/register"
 	"github.com/mszlu521/msgo/tracer"
 	"github.com/opentracing/opentracing-go"
 	"github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
@@ -92,12 +94,15 @@ func main() {
 	//tcpServer.LimiterTimeOut = time.Second
 	//tcpServer.SetLimiter(10, 100)
 	//tcpServer.Run()
-	//cli := register.MsEtcdRegister{}
-	//cli.CreateCli(register.Option{
-	//	Endpoints:   []string{"127.0.0.1:2379"},
-	//	DialTimeout: 5 * time.Second,
-	//})
-	//cli.RegisterService("goodsCenter", "127.0.0.1", 9002)
+	engine.RegisterType = "etcd"
+	engine.RegisterOption = register.Option{
+		Endpoints:   []string{"127.0.0.1:2379"},
+		DialTimeout: 5 * time.Second,
+		ServiceName: "goodsCenter",
+		Host:        "127.0.0.1",
+		TTL:         10 * time.Second,
+	}
+	engine.AutoRegister = true
 	engine.Run(":9002")
 
 }
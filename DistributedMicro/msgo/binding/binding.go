@@ -1,6 +1,9 @@
 package binding
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 type Binding interface {
 	Name() string
@@ -8,6 +11,59 @@ type Binding interface {
 }
 
 var (
-	JSON = jsonBinding{}
-	XML  = xmlBinding{}
+	JSON          = jsonBinding{}
+	XML           = xmlBinding{}
+	Form          = formBinding{}
+	Query         = queryBinding{}
+	FormMultipart = formMultipartBinding{}
+	URI           = uriBinding{}
+	YAML          = yamlBinding{}
+	ProtoBuf      = protobufBinding{}
+	MsgPack       = msgpackBinding{}
+)
+
+const (
+	MIMEJSON              = "application/json"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+	MIMEYAML              = "application/x-yaml"
+	MIMEYAML2             = "application/yaml"
+	MIMEPROTOBUF          = "application/x-protobuf"
+	MIMEMSGPACK           = "application/msgpack"
+	MIMEMSGPACK2          = "application/x-msgpack"
 )
+
+// Default 根据请求方法和 Content-Type 选择绑定器 没有 body 的请求绑定 url 中的参数
+func Default(method string, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return Query
+	}
+	switch filterFlags(contentType) {
+	case MIMEJSON:
+		return JSON
+	case MIMEXML, MIMEXML2:
+		return XML
+	case MIMEPOSTForm:
+		return Form
+	case MIMEMultipartPOSTForm:
+		return FormMultipart
+	case MIMEYAML, MIMEYAML2:
+		return YAML
+	case MIMEPROTOBUF:
+		return ProtoBuf
+	case MIMEMSGPACK, MIMEMSGPACK2:
+		return MsgPack
+	default:
+		return Form
+	}
+}
+
+// filterFlags application/json; charset=utf-8 -> application/json
+func filterFlags(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
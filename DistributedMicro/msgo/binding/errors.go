@@ -0,0 +1,145 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
+	"strings"
+	"sync"
+)
+
+// FieldError 单个字段的校验错误 Field 是参数路径 如 items[0].name
+// 表单和查询参数绑定时使用 form query 标签 没有时使用 json 标签
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message,omitempty"`
+	fe      validator.FieldError
+}
+
+func (e *FieldError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Param != "" {
+		return fmt.Sprintf("field [%s] failed on the [%s=%s] rule", e.Field, e.Rule, e.Param)
+	}
+	return fmt.Sprintf("field [%s] failed on the [%s] rule", e.Field, e.Rule)
+}
+
+// ValidationError 参数校验失败的所有字段 前端可以按 Field 标出对应的表单项
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Translate 返回翻译成 locale 的副本 没有对应的翻译器时使用 DefaultLocale
+func (e ValidationError) Translate(locale string) ValidationError {
+	trans := Translator(locale)
+	result := make(ValidationError, len(e))
+	for i, fe := range e {
+		copied := *fe
+		if trans != nil {
+			copied.Message = translate(trans, fe)
+		}
+		result[i] = &copied
+	}
+	return result
+}
+
+func translate(trans ut.Translator, fe *FieldError) string {
+	if fe.fe != nil {
+		return fe.fe.Translate(trans)
+	}
+	//不是 validator 产生的错误 按规则名查找翻译 如 msgo:"required"
+	if message, err := trans.T(fe.Rule, fe.Field, fe.Param); err == nil {
+		return message
+	}
+	return fe.Error()
+}
+
+// toValidationError 把 validator 的错误转换为带 json 路径的 ValidationError
+func toValidationError(err error) error {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	result := make(ValidationError, 0, len(errs))
+	for _, fe := range errs {
+		//Namespace 为 User.items[0].name 去掉最外层的结构体名
+		path := fe.Namespace()
+		if i := strings.IndexByte(path, '.'); i >= 0 {
+			path = path[i+1:]
+		}
+		result = append(result, &FieldError{Field: path, Rule: fe.Tag(), Param: fe.Param(), fe: fe})
+	}
+	return result
+}
+
+// DefaultLocale 请求的语言没有对应的翻译器时使用
+var DefaultLocale = "zh"
+
+var (
+	translatorOnce sync.Once
+	translatorMu   sync.RWMutex
+	translators    = make(map[string]ut.Translator)
+)
+
+// RegisterTranslator 注册翻译器 register 把校验规则的翻译注册到 Validator.Engine() 上
+// validator 自带的翻译可以直接使用 如 ja.RegisterDefaultTranslations
+func RegisterTranslator(trans ut.Translator, register func(v *validator.Validate, trans ut.Translator) error) error {
+	//先注册内置的翻译器 避免之后覆盖自定义的同名翻译器
+	translatorOnce.Do(registerDefaultTranslators)
+	return registerTranslator(trans, register)
+}
+
+func registerTranslator(trans ut.Translator, register func(v *validator.Validate, trans ut.Translator) error) error {
+	v, ok := Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("the validator engine is not *validator.Validate")
+	}
+	if err := register(v, trans); err != nil {
+		return err
+	}
+	translatorMu.Lock()
+	translators[trans.Locale()] = trans
+	translatorMu.Unlock()
+	return nil
+}
+
+// Translator 查找 locale 对应的翻译器 zh-CN 找不到时使用 zh
+func Translator(locale string) ut.Translator {
+	translatorOnce.Do(registerDefaultTranslators)
+	locale = strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+	translatorMu.RLock()
+	defer translatorMu.RUnlock()
+	if trans, ok := translators[locale]; ok {
+		return trans
+	}
+	if i := strings.IndexByte(locale, '_'); i > 0 {
+		if trans, ok := translators[locale[:i]]; ok {
+			return trans
+		}
+	}
+	return translators[DefaultLocale]
+}
+
+// registerDefaultTranslators 内置 zh 和 en 自定义的校验器不是 validator 时忽略
+func registerDefaultTranslators() {
+	uni := ut.New(en.New(), en.New(), zh.New())
+	enTrans, _ := uni.GetTranslator("en")
+	zhTrans, _ := uni.GetTranslator("zh")
+	_ = registerTranslator(enTrans, enTranslations.RegisterDefaultTranslations)
+	_ = registerTranslator(zhTrans, zhTranslations.RegisterDefaultTranslations)
+}
@@ -0,0 +1,81 @@
+package binding
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Item struct {
+	Name  string `json:"name" validate:"required"`
+	Count int    `json:"count" validate:"gte=1"`
+}
+
+type Order struct {
+	Title string  `json:"title" validate:"required,max=5"`
+	Items []*Item `json:"items" validate:"dive"`
+}
+
+func TestValidationError(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"title":"too long title","items":[{"name":"a","count":1},{"count":0}]}`))
+	err := JSON.Bind(r, &Order{})
+	var errs ValidationError
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+	want := []FieldError{
+		{Field: "title", Rule: "max", Param: "5"},
+		{Field: "items[1].name", Rule: "required"},
+		{Field: "items[1].count", Rule: "gte", Param: "1"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %v", errs)
+	}
+	for i, w := range want {
+		if errs[i].Field != w.Field || errs[i].Rule != w.Rule || errs[i].Param != w.Param {
+			t.Errorf("errs[%d] = %+v, want %+v", i, *errs[i], w)
+		}
+	}
+	if msg := errs.Translate("zh-CN")[1].Message; msg != "name为必填字段" {
+		t.Errorf("zh message = %q", msg)
+	}
+	if msg := errs.Translate("en-US")[1].Message; msg != "name is a required field" {
+		t.Errorf("en message = %q", msg)
+	}
+	if errs[1].Message != "" {
+		t.Error("Translate must not modify the original errors")
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`[{"name":"a","count":1},{"name":"b","count":0}]`))
+	if err := JSON.Bind(r, &[]Item{}); !errors.As(err, &errs) || errs[0].Field != "[1].count" {
+		t.Errorf("slice err = %v", err)
+	}
+}
+
+func TestValidationErrorFormTag(t *testing.T) {
+	type Profile struct {
+		Nick string `form:"nick_name" validate:"required"`
+	}
+	type User struct {
+		Name    string   `form:"user_name" json:"name" validate:"required"`
+		Age     int      `json:"age" validate:"gte=1"`
+		Profile *Profile `form:"profile" validate:"required"`
+	}
+	r := httptest.NewRequest("GET", "/?age=0", nil)
+	user := User{Profile: &Profile{}}
+	err := Query.Bind(r, &user)
+	var errs ValidationError
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ValidationError", err)
+	}
+	want := []string{"user_name", "age", "profile.nick_name"}
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %v", errs)
+	}
+	for i, field := range want {
+		if errs[i].Field != field {
+			t.Errorf("errs[%d].Field = %q, want %q", i, errs[i].Field, field)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package binding
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+const defaultMemory = 32 << 20
+
+type formBinding struct{}
+type queryBinding struct{}
+type formMultipartBinding struct{}
+type uriBinding struct{}
+
+func (formBinding) Name() string {
+	return "form"
+}
+
+// Bind 同时解析 url 中的参数和 x-www-form-urlencoded/multipart 的 body
+func (formBinding) Bind(r *http.Request, obj any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if err := r.ParseMultipartForm(defaultMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	if err := mapForm(obj, r.Form, "form"); err != nil {
+		return err
+	}
+	return validateTags(obj, "form")
+}
+
+func (queryBinding) Name() string {
+	return "query"
+}
+
+// Bind 优先使用 query 标签 没有时使用 form 标签
+func (queryBinding) Bind(r *http.Request, obj any) error {
+	if err := mapForm(obj, r.URL.Query(), "query", "form"); err != nil {
+		return err
+	}
+	return validateTags(obj, "query", "form")
+}
+
+func (formMultipartBinding) Name() string {
+	return "multipart/form-data"
+}
+
+func (formMultipartBinding) Bind(r *http.Request, obj any) error {
+	if err := r.ParseMultipartForm(defaultMemory); err != nil {
+		return err
+	}
+	if err := mapForm(obj, r.MultipartForm.Value, "form"); err != nil {
+		return err
+	}
+	return validateTags(obj, "form")
+}
+
+func (uriBinding) Name() string {
+	return "uri"
+}
+
+// BindUri 路由参数 /user/:id 使用 uri:"id" 标签
+func (uriBinding) BindUri(params map[string][]string, obj any) error {
+	if err := mapForm(obj, params, "uri"); err != nil {
+		return err
+	}
+	return validateTags(obj, "uri")
+}
+
+// MapUri 只填充路由参数 不做校验 后续还有其他绑定时使用
+// obj 不是结构体指针时(如 map 切片)没有可以填充的字段 直接返回
+func MapUri(params map[string][]string, obj any) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return mapForm(obj, params, "uri")
+}
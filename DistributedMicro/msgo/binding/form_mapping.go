@@ -0,0 +1,284 @@
+package binding
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// mapForm 按结构体标签把 url.Values 填充到结构体中
+// tags 按顺序查找 第一个存在的标签生效 标签为 - 的字段跳过 没有标签时使用字段名
+// form:"age,default=18" 没有传值时使用默认值
+// time_format:"2006-01-02" 指定时间格式 也可以是 unix unixmilli
+// 嵌套的结构体使用 address.city 或者 address[city]，匿名嵌入的结构体字段直接展开
+func mapForm(obj any, form map[string][]string, tags ...string) error {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return errors.New("This argument must have a pointer type")
+	}
+	value = value.Elem()
+	if value.Kind() != reflect.Struct {
+		return errors.New("This argument must be a pointer to struct")
+	}
+	return mapStruct(value, form, "", tags)
+}
+
+func mapStruct(value reflect.Value, form map[string][]string, prefix string, tags []string) error {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, defaultValue, hasDefault := lookupTag(field, tags)
+		if name == "-" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if isStruct(field.Type) && name == "" && field.Anonymous {
+			if err := mapNested(fieldValue, form, prefix, tags, true); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		key := prefix + name
+		if isStruct(field.Type) {
+			if err := mapNested(fieldValue, form, key+".", tags, false); err != nil {
+				return err
+			}
+			continue
+		}
+		values, ok := lookupValues(form, key)
+		if !ok && hasDefault {
+			values, ok = []string{defaultValue}, true
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(fieldValue, values, field); err != nil {
+			return fmt.Errorf("field [%s]: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// mapNested 结构体的指针只有在有对应参数时才创建
+func mapNested(value reflect.Value, form map[string][]string, prefix string, tags []string, embedded bool) error {
+	if value.Kind() != reflect.Pointer {
+		return mapStruct(value, form, prefix, tags)
+	}
+	if value.IsNil() {
+		if !embedded && !hasPrefix(form, prefix) {
+			return nil
+		}
+		if !value.CanSet() {
+			return nil
+		}
+		value.Set(reflect.New(value.Type().Elem()))
+	}
+	return mapStruct(value.Elem(), form, prefix, tags)
+}
+
+func lookupTag(field reflect.StructField, tags []string) (name string, defaultValue string, hasDefault bool) {
+	for _, tag := range tags {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		options := strings.Split(value, ",")
+		name = options[0]
+		for _, option := range options[1:] {
+			if strings.HasPrefix(option, "default=") {
+				defaultValue, hasDefault = strings.TrimPrefix(option, "default="), true
+			}
+		}
+		return
+	}
+	return
+}
+
+// lookupValues address.city 找不到时再找 address[city]
+func lookupValues(form map[string][]string, key string) ([]string, bool) {
+	if values, ok := form[key]; ok && len(values) > 0 {
+		return values, true
+	}
+	if !strings.Contains(key, ".") {
+		return nil, false
+	}
+	parts := strings.Split(key, ".")
+	values, ok := form[parts[0]+"["+strings.Join(parts[1:], "][")+"]"]
+	return values, ok && len(values) > 0
+}
+
+func hasPrefix(form map[string][]string, prefix string) bool {
+	// a.b. -> a[b][
+	parts := strings.Split(strings.TrimSuffix(prefix, "."), ".")
+	bracket := parts[0] + "["
+	if len(parts) > 1 {
+		bracket += strings.Join(parts[1:], "][") + "]["
+	}
+	for key := range form {
+		if strings.HasPrefix(key, prefix) || strings.HasPrefix(key, bracket) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStruct 需要展开的结构体 time.Time 和实现了 TextUnmarshaler 的类型按值处理
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(value reflect.Value, values []string, field reflect.StructField) error {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setField(value.Elem(), values, field)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			value.SetBytes([]byte(values[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(value.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(slice.Index(i), v, field); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) > value.Len() {
+			return fmt.Errorf("%d values overflow array of length %d", len(values), value.Len())
+		}
+		for i, v := range values {
+			if err := setValue(value.Index(i), v, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setValue(value, values[0], field)
+}
+
+func setValue(value reflect.Value, s string, field reflect.StructField) error {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setValue(value.Elem(), s, field)
+	}
+	switch value.Type() {
+	case timeType:
+		return setTime(value, s, field)
+	case durationType:
+		if s == "" {
+			s = "0"
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseInt(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseFloat(s, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(n)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// setTime 默认格式 RFC3339 time_format:"unix" 表示秒级时间戳
+func setTime(value reflect.Value, s string, field reflect.StructField) error {
+	if s == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get("time_format")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	switch layout {
+	case "unix", "unixmilli":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if layout == "unixmilli" {
+			t = time.UnixMilli(n)
+		}
+		value.Set(reflect.ValueOf(t))
+		return nil
+	}
+	t, err := time.ParseInLocation(layout, s, time.Local)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
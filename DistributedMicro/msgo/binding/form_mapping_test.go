@@ -0,0 +1,77 @@
+package binding
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type Address struct {
+	City   string `form:"city"`
+	Street string `form:"street,default=none"`
+}
+
+type Page struct {
+	Page int `form:"page,default=1"`
+	Size int `form:"size,default=10" validate:"max=100"`
+}
+
+type SearchForm struct {
+	Page
+	Keyword  string    `query:"q" form:"keyword"`
+	Ids      []int64   `form:"id"`
+	Price    *float64  `form:"price"`
+	Online   bool      `form:"online"`
+	Since    time.Time `form:"since" time_format:"2006-01-02"`
+	Created  time.Time `form:"created" time_format:"unix"`
+	Timeout  time.Duration
+	Address  Address  `form:"address"`
+	Company  *Address `form:"company"`
+	Internal string   `form:"-"`
+}
+
+func TestQueryBinding(t *testing.T) {
+	r := httptest.NewRequest("GET", "/search?q=phone&id=1&id=2&price=9.9&online=true"+
+		"&since=2022-07-01&created=1656633600&Timeout=3s&address.city=shanghai&company[city]=beijing&Internal=x", nil)
+	form := &SearchForm{}
+	if err := Query.Bind(r, form); err != nil {
+		t.Fatal(err)
+	}
+	if form.Keyword != "phone" || len(form.Ids) != 2 || form.Ids[1] != 2 || form.Price == nil || *form.Price != 9.9 || !form.Online {
+		t.Errorf("form = %+v", form)
+	}
+	if form.Page.Page != 1 || form.Size != 10 {
+		t.Errorf("defaults not applied: %+v", form.Page)
+	}
+	if form.Since.Format("2006-01-02") != "2022-07-01" || form.Created.Unix() != 1656633600 || form.Timeout != 3*time.Second {
+		t.Errorf("time fields: %v %v %v", form.Since, form.Created, form.Timeout)
+	}
+	if form.Address.City != "shanghai" || form.Address.Street != "none" || form.Company == nil || form.Company.City != "beijing" {
+		t.Errorf("nested: %+v %+v", form.Address, form.Company)
+	}
+	if form.Internal != "" {
+		t.Errorf("ignored field was set: %s", form.Internal)
+	}
+
+	r = httptest.NewRequest("GET", "/search?size=1000", nil)
+	if err := Query.Bind(r, &SearchForm{}); err == nil {
+		t.Error("expected validation error for size=1000")
+	}
+	r = httptest.NewRequest("GET", "/search?id=abc", nil)
+	if err := Query.Bind(r, &SearchForm{}); err == nil {
+		t.Error("expected conversion error for id=abc")
+	}
+}
+
+func TestURIBinding(t *testing.T) {
+	var obj struct {
+		Id   int64  `uri:"id" validate:"required"`
+		Name string `uri:"name"`
+	}
+	if err := URI.BindUri(map[string][]string{"id": {"10"}, "name": {"msgo"}}, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Id != 10 || obj.Name != "msgo" {
+		t.Errorf("obj = %+v", obj)
+	}
+}
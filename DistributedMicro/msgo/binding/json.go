@@ -1,11 +1,14 @@
 package binding
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 type jsonBinding struct {
@@ -23,91 +26,142 @@ func (b jsonBinding) Bind(r *http.Request, obj any) error {
 	if body == nil {
 		return errors.New("invalid request")
 	}
-	decoder := json.NewDecoder(body)
-	if b.DisallowUnknownFields {
-		decoder.DisallowUnknownFields()
-	}
-	if b.IsValidate {
-		err := validateParam(obj, decoder)
-		if err != nil {
-			return err
-		}
-	} else {
-		err := decoder.Decode(obj)
-		if err != nil {
+	if !b.IsValidate {
+		if err := b.decode(json.NewDecoder(body), obj); err != nil {
 			return err
 		}
+		return validate(obj)
+	}
+	//先读出原始的 json 检查 msgo:"required" 再解析到 obj 数字不会经过 float64
+	var raw json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return err
+	}
+	if err := validateParam(obj, raw); err != nil {
+		return err
+	}
+	if err := b.decode(json.NewDecoder(bytes.NewReader(raw)), obj); err != nil {
+		return err
 	}
 	return validate(obj)
 }
 
-func validateParam(obj any, decoder *json.Decoder) error {
+func (b jsonBinding) decode(decoder *json.Decoder, obj any) error {
+	if b.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(obj)
+}
 
+// validateParam 检查 msgo:"required" 的字段是否存在 嵌套的结构体 指针和切片都会检查
+// 缺少的字段使用完整的路径 如 items[0].sku.id
+func validateParam(obj any, raw json.RawMessage) error {
 	//反射
 	valueOf := reflect.ValueOf(obj)
 	//判断其是否为指针类型
 	if valueOf.Kind() != reflect.Pointer {
 		return errors.New("This argument must have a pointer type")
 	}
-	elem := valueOf.Elem().Interface()
-	of := reflect.ValueOf(elem)
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	//数字解析为 json.Number 保留 int64 的精度
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return err
+	}
+	var errs ValidationError
+	checkParam(valueOf.Type().Elem(), data, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
 
-	switch of.Kind() {
+// checkParam 按类型遍历一次解析出的 json 类型不匹配的值留给 json.Unmarshal 报错
+func checkParam(t reflect.Type, data any, path string, errs *ValidationError) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if data == nil || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
 	case reflect.Struct:
-		return checkParam(of, obj, decoder)
+		if m, ok := data.(map[string]any); ok {
+			checkStruct(t, m, path, errs)
+		}
 	case reflect.Slice, reflect.Array:
-		elem := of.Type().Elem()
-		if elem.Kind() == reflect.Struct {
-			return checkParamSlice(elem, obj, decoder)
+		values, _ := data.([]any)
+		for i, value := range values {
+			checkParam(t.Elem(), value, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		m, _ := data.(map[string]any)
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			checkParam(t.Elem(), m[key], joinPath(path, key), errs)
 		}
-	default:
-		_ = decoder.Decode(obj)
 	}
-	return nil
 }
 
-func checkParamSlice(of reflect.Type, obj any, decoder *json.Decoder) error {
-	mapValue := make([]map[string]interface{}, 0)
-	_ = decoder.Decode(&mapValue)
-	for i := 0; i < of.NumField(); i++ {
-		field := of.Field(i)
+func checkStruct(t reflect.Type, m map[string]any, path string, errs *ValidationError) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
 		name := field.Name
-		jsonName := field.Tag.Get("json")
+		jsonName := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if jsonName == "-" {
+			continue
+		}
+		//匿名嵌入的结构体 字段和外层在同一个 json 对象中
+		if field.Anonymous && jsonName == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				checkStruct(embedded, m, path, errs)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
 		if jsonName != "" {
 			name = jsonName
 		}
-		required := field.Tag.Get("msgo")
-		for _, v := range mapValue {
-			value := v[name]
-			if value == nil && required == "required" {
-				return errors.New(fmt.Sprintf("filed [%s] is not exist,because [%s] is required", jsonName, jsonName))
+		value := lookupKey(m, name)
+		if value == nil {
+			if field.Tag.Get("msgo") == "required" {
+				*errs = append(*errs, &FieldError{Field: joinPath(path, name), Rule: "required"})
 			}
+			continue
 		}
+		checkParam(field.Type, value, joinPath(path, name), errs)
 	}
-	b, _ := json.Marshal(mapValue)
-	_ = json.Unmarshal(b, obj)
-	return nil
 }
 
-func checkParam(of reflect.Value, obj any, decoder *json.Decoder) error {
-	//解析为map，然后根据map中的key 进行比对
-	//判断类型 结构体 才能解析为map
-	mapValue := make(map[string]interface{})
-	_ = decoder.Decode(&mapValue)
-	for i := 0; i < of.NumField(); i++ {
-		field := of.Type().Field(i)
-		name := field.Name
-		jsonName := field.Tag.Get("json")
-		if jsonName != "" {
-			name = jsonName
-		}
-		required := field.Tag.Get("msgo")
-		value := mapValue[name]
-		if value == nil && required == "required" {
-			return errors.New(fmt.Sprintf("filed [%s] is not exist,because [%s] is required", jsonName, jsonName))
+// lookupKey 和 encoding/json 一样 找不到时忽略大小写再找一次
+func lookupKey(m map[string]any, name string) any {
+	if value, ok := m[name]; ok {
+		return value
+	}
+	for key, value := range m {
+		if strings.EqualFold(key, name) {
+			return value
 		}
 	}
-	b, _ := json.Marshal(mapValue)
-	_ = json.Unmarshal(b, obj)
 	return nil
 }
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,67 @@
+package binding
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Sku struct {
+	Id    int64  `json:"id" msgo:"required"`
+	Color string `json:"color"`
+}
+
+type Base struct {
+	Tenant string `json:"tenant" msgo:"required"`
+}
+
+type OrderItem struct {
+	Sku   *Sku `json:"sku" msgo:"required"`
+	Count int  `json:"count"`
+}
+
+type CreateOrder struct {
+	Base
+	User *struct {
+		Name string `json:"name" msgo:"required"`
+	} `json:"user"`
+	Items []OrderItem `json:"items" msgo:"required"`
+}
+
+func TestJSONRequired(t *testing.T) {
+	bind := JSON
+	bind.IsValidate = true
+
+	body := `{"tenant":"t1","user":{"name":"msgo"},"items":[{"sku":{"id":9007199254740993}}]}`
+	order := &CreateOrder{}
+	if err := bind.Bind(httptest.NewRequest("POST", "/", strings.NewReader(body)), order); err != nil {
+		t.Fatal(err)
+	}
+	//超过 float64 精度的 int64 不能丢失
+	if order.Items[0].Sku.Id != 9007199254740993 || order.User.Name != "msgo" {
+		t.Errorf("order = %+v", order)
+	}
+
+	body = `{"user":{},"items":[{"sku":{"id":1}},{"count":1},{"sku":{"color":"red"}}]}`
+	err := bind.Bind(httptest.NewRequest("POST", "/", strings.NewReader(body)), &CreateOrder{})
+	var errs ValidationError
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v", err)
+	}
+	want := []string{"tenant", "user.name", "items[1].sku", "items[2].sku.id"}
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %v", errs)
+	}
+	for i, field := range want {
+		if errs[i].Field != field || errs[i].Rule != "required" {
+			t.Errorf("errs[%d] = %+v, want %s", i, *errs[i], field)
+		}
+	}
+
+	body = `[{"sku":{"id":1}},{"sku":null}]`
+	err = bind.Bind(httptest.NewRequest("POST", "/", strings.NewReader(body)), &[]OrderItem{})
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != "[1].sku" {
+		t.Errorf("slice err = %v", err)
+	}
+}
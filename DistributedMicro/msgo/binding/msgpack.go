@@ -0,0 +1,24 @@
+package binding
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+type msgpackBinding struct {
+}
+
+func (msgpackBinding) Name() string {
+	return "msgpack"
+}
+
+func (b msgpackBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return nil
+	}
+	decoder := msgpack.NewDecoder(r.Body)
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,34 @@
+package binding
+
+import (
+	"errors"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+type protobufBinding struct {
+}
+
+func (protobufBinding) Name() string {
+	return "protobuf"
+}
+
+// Bind obj 必须是 proto.Message
+func (b protobufBinding) Bind(r *http.Request, obj any) error {
+	message, ok := obj.(proto.Message)
+	if !ok {
+		return errors.New("obj is not a proto.Message")
+	}
+	if r.Body == nil {
+		return nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(data, message); err != nil {
+		return err
+	}
+	return validate(obj)
+}
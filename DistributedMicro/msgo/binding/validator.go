@@ -1,6 +1,7 @@
 package binding
 
 import (
+	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"reflect"
@@ -55,16 +56,34 @@ func (d *defaultValidator) ValidateStruct(obj any) error {
 		return d.validateStruct(obj)
 	case reflect.Slice, reflect.Array:
 		count := of.Len()
-		sliceValidationError := make(SliceValidationError, 0)
+		validationError := make(ValidationError, 0)
 		for i := 0; i < count; i++ {
-			if err := d.validateStruct(of.Index(i).Interface()); err != nil {
-				sliceValidationError = append(sliceValidationError, err)
+			//map 等不是结构体的元素没有校验规则
+			elem := of.Index(i)
+			for (elem.Kind() == reflect.Pointer || elem.Kind() == reflect.Interface) && !elem.IsNil() {
+				elem = elem.Elem()
 			}
+			if elem.Kind() != reflect.Struct {
+				continue
+			}
+			err := d.validateStruct(of.Index(i).Interface())
+			if err == nil {
+				continue
+			}
+			var errs ValidationError
+			if !errors.As(err, &errs) {
+				return err
+			}
+			//字段路径加上元素下标 [0].name
+			for _, fe := range errs {
+				fe.Field = fmt.Sprintf("[%d].%s", i, fe.Field)
+			}
+			validationError = append(validationError, errs...)
 		}
-		if len(sliceValidationError) == 0 {
+		if len(validationError) == 0 {
 			return nil
 		}
-		return sliceValidationError
+		return validationError
 	}
 	return nil
 }
@@ -77,14 +96,96 @@ func (d *defaultValidator) Engine() any {
 func (d *defaultValidator) lazyInit() {
 	d.one.Do(func() {
 		d.validate = validator.New()
+		//错误中的字段名使用 json 标签 和请求中的参数名一致
+		d.validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
 	})
 }
 
 func (d *defaultValidator) validateStruct(obj any) error {
 	d.lazyInit()
-	return d.validate.Struct(obj)
+	return toValidationError(d.validate.Struct(obj))
 }
 
 func validate(obj any) error {
 	return Validator.ValidateStruct(obj)
 }
+
+// validateTags 表单 查询参数等绑定使用 错误中的字段名按 tags 的顺序取标签 都没有时使用 json 标签
+// 和客户端传来的参数名一致 如 form:"user_name" 报错为 user_name
+func validateTags(obj any, tags ...string) error {
+	err := validate(obj)
+	var errs ValidationError
+	if !errors.As(err, &errs) {
+		return err
+	}
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return err
+	}
+	tags = append(tags, "json")
+	for _, fe := range errs {
+		if fe.fe == nil {
+			continue
+		}
+		if path, ok := fieldPath(t, fe.fe.StructNamespace(), tags); ok {
+			fe.Field = path
+		}
+	}
+	return err
+}
+
+// fieldPath 把 User.Items[0].Name 这样的结构体路径转换成标签名的路径 items[0].name
+func fieldPath(t reflect.Type, namespace string, tags []string) (string, bool) {
+	segments := strings.Split(namespace, ".")[1:]
+	names := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return "", false
+		}
+		name, index := segment, ""
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return "", false
+		}
+		names = append(names, tagName(field, tags)+index)
+		t = field.Type
+		//下标可能有多层 如 [0][1]
+		for n := strings.Count(index, "["); n > 0; n-- {
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			default:
+				return "", false
+			}
+		}
+	}
+	return strings.Join(names, "."), true
+}
+
+func tagName(field reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,24 @@
+package binding
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type yamlBinding struct {
+}
+
+func (yamlBinding) Name() string {
+	return "yaml"
+}
+
+func (b yamlBinding) Bind(r *http.Request, obj any) error {
+	if r.Body == nil {
+		return nil
+	}
+	decoder := yaml.NewDecoder(r.Body)
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	return validate(obj)
+}
@@ -0,0 +1,31 @@
+package msgo
+
+import "net/http"
+
+// BodyLimit 限制请求体的大小 超过 n 字节时返回 413
+// Content-Length 已经超过时直接拒绝 否则在读取 body 时检查 绑定参数失败同样返回 413
+func BodyLimit(n int64) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if !limitBody(ctx, n) {
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// limitBody Content-Length 超过限制时返回 413 并返回 false
+func limitBody(ctx *Context, n int64) bool {
+	if n <= 0 {
+		return true
+	}
+	if ctx.R.ContentLength > n {
+		//不读取剩余的 body 响应后关闭连接
+		ctx.W.Header().Set("Connection", "close")
+		ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		return false
+	}
+	ctx.R.Body = maxBytesReader(ctx.R.Body, n)
+	return true
+}
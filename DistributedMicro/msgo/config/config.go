@@ -4,7 +4,9 @@ import (
 	"flag"
 	"github.com/BurntSushi/toml"
 	msLog "github.com/mszlu521/msgo/log"
+	"io"
 	"os"
+	"strings"
 )
 
 var Conf = &MsConfig{
@@ -16,6 +18,7 @@ type MsConfig struct {
 	Log      map[string]any
 	Pool     map[string]any
 	Template map[string]any
+	Server   map[string]any
 }
 
 func init() {
@@ -24,7 +27,8 @@ func init() {
 
 func loadToml() {
 	configFile := flag.String("conf", "conf/app.toml", "app config file")
-	flag.Parse()
+	//init 中不能调用 flag.Parse，否则使用方和 go test 后注册的参数都会解析失败
+	parseConfFlag(configFile)
 	if _, err := os.Stat(*configFile); err != nil {
 		Conf.logger.Info("conf/app.toml file not load，because not exist")
 		return
@@ -35,3 +39,21 @@ func loadToml() {
 		return
 	}
 }
+
+// parseConfFlag 只从命令行中解析 -conf 参数
+func parseConfFlag(configFile *string) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(configFile, "conf", *configFile, "app config file")
+	args := os.Args[1:]
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "conf" || strings.HasPrefix(name, "conf=") {
+			_ = fs.Parse(args[i:])
+			return
+		}
+	}
+}
@@ -21,7 +21,10 @@ const defaultMultipartMemory = 32 << 20 //32M
 type Context struct {
 	W                     http.ResponseWriter
 	R                     *http.Request
+	writer                responseWriter
 	engine                *Engine
+	params                Params
+	aborted               bool
 	queryCache            url.Values
 	formCache             url.Values
 	DisallowUnknownFields bool
@@ -31,6 +34,45 @@ type Context struct {
 	Keys                  map[string]any
 	mu                    sync.RWMutex
 	sameSite              http.SameSite
+	uploadPolicy          *UploadPolicy
+}
+
+func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
+	c.writer.reset(w)
+	c.W = &c.writer
+	c.R = r
+	c.params = c.params[:0]
+	c.queryCache = nil
+	c.formCache = nil
+	c.StatusCode = 0
+	c.Keys = nil
+	c.aborted = false
+	c.uploadPolicy = nil
+}
+
+// Writer 可以获取响应的状态码 大小 以及是否已经写出
+func (c *Context) Writer() ResponseWriter {
+	return &c.writer
+}
+
+// Abort 阻止调用后续的中间件和处理函数 已经在执行的中间件不受影响
+func (c *Context) Abort() {
+	c.aborted = true
+}
+
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
+
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.W.WriteHeader(code)
+	c.StatusCode = code
+}
+
+func (c *Context) AbortWithStatusJSON(code int, data any) error {
+	c.Abort()
+	return c.JSON(code, data)
 }
 
 func (c *Context) SetSameSite(s http.SameSite) {
@@ -52,6 +94,15 @@ func (c *Context) Get(key string) (value any, ok bool) {
 	return
 }
 
+// Param 获取路由中的参数 /user/get/:id -> ctx.Param("id")
+func (c *Context) Param(key string) string {
+	return c.params.ByName(key)
+}
+
+func (c *Context) Params() Params {
+	return c.params
+}
+
 func (c *Context) SetBasicAuth(username, password string) {
 	c.R.Header.Set("Authorization", "Basic "+BasicAuth(username, password))
 }
@@ -119,7 +170,7 @@ func (c *Context) get(cache map[string][]string, key string) (map[string]string,
 
 func (c *Context) initPostFormCache() {
 	if c.R != nil {
-		if err := c.R.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		if err := c.R.ParseMultipartForm(c.maxMemory()); err != nil {
 			if !errors.Is(err, http.ErrNotMultipart) {
 				log.Println(err)
 			}
@@ -157,24 +208,48 @@ func (c *Context) GetPostFormMap(key string) (map[string]string, bool) {
 	return c.get(c.formCache, key)
 }
 
-func (c *Context) FormFile(name string) *multipart.FileHeader {
+// FormFile 文件不存在或者不符合 Upload 设置的限制时返回错误
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.R.MultipartForm == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, err
+		}
+	}
 	file, header, err := c.R.FormFile(name)
 	if err != nil {
-		log.Println(err)
+		return nil, err
 	}
-	defer file.Close()
-	return header
+	file.Close()
+	if err := c.checkFile(header); err != nil {
+		return nil, err
+	}
+	return header, nil
 }
 
-func (c *Context) FormFiles(name string) []*multipart.FileHeader {
+func (c *Context) FormFiles(name string) ([]*multipart.FileHeader, error) {
 	multipartForm, err := c.MultipartForm()
 	if err != nil {
-		return make([]*multipart.FileHeader, 0)
+		return nil, err
+	}
+	files := multipartForm.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	for _, file := range files {
+		if err := c.checkFile(file); err != nil {
+			return nil, err
+		}
 	}
-	return multipartForm.File[name]
+	return files, nil
 }
 
+// SaveUploadedFile 保存到 dst dst 不要直接使用客户端传来的 file.Filename 可以用 SafeFilename 处理或者使用 SaveFile
+//
+// Deprecated: 不检查 dst 是否在上传目录之内 使用 SaveFile 和 DiskStorage
 func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	if err := c.checkFile(file); err != nil {
+		return err
+	}
 	src, err := file.Open()
 	if err != nil {
 		return err
@@ -185,15 +260,19 @@ func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-
 	_, err = io.Copy(out, src)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
 	return err
 }
 
 func (c *Context) MultipartForm() (*multipart.Form, error) {
-	err := c.R.ParseMultipartForm(defaultMultipartMemory)
-	return c.R.MultipartForm, err
+	err := c.R.ParseMultipartForm(c.maxMemory())
+	if err != nil {
+		return nil, c.bodyError(err)
+	}
+	return c.R.MultipartForm, nil
 }
 
 func (c *Context) HTML(status int, html string) error {
@@ -202,35 +281,44 @@ func (c *Context) HTML(status int, html string) error {
 }
 
 func (c *Context) HTMLTemplate(name string, data any, filenames ...string) error {
-	//状态是200 默认不设置的话 如果调用了 write这个方法 实际上默认返回状态 200
-	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
-	t := template.New(name)
-	t, err := t.ParseFiles(filenames...)
+	t, err := c.engine.parseTemplate("files:"+strings.Join(filenames, ","), func(t *template.Template) (*template.Template, error) {
+		return t.ParseFiles(filenames...)
+	})
 	if err != nil {
 		return err
 	}
-	err = t.Execute(c.W, data)
-	return err
+	return c.templateStatus(http.StatusOK, t, name, data)
 }
 
 func (c *Context) HTMLTemplateGlob(name string, data any, pattern string) error {
-	//状态是200 默认不设置的话 如果调用了 write这个方法 实际上默认返回状态 200
-	c.W.Header().Set("Content-Type", "text/html; charset=utf-8")
-	t := template.New(name)
-	t, err := t.ParseGlob(pattern)
+	t, err := c.engine.parseTemplate("glob:"+pattern, func(t *template.Template) (*template.Template, error) {
+		return t.ParseGlob(pattern)
+	})
 	if err != nil {
 		return err
 	}
-	err = t.Execute(c.W, data)
-	return err
+	return c.templateStatus(http.StatusOK, t, name, data)
 }
 
+// Template 渲染 LoadTemplate 加载的模板
 func (c *Context) Template(name string, data any) error {
-	//状态是200 默认不设置的话 如果调用了 write这个方法 实际上默认返回状态 200
-	return c.Render(http.StatusOK, &render.HTML{
+	return c.TemplateStatus(http.StatusOK, name, data)
+}
+
+// TemplateStatus 模板执行成功后才写出状态码和响应头
+func (c *Context) TemplateStatus(status int, name string, data any) error {
+	t, execName, err := c.engine.HTMLRender.Lookup(name)
+	if err != nil {
+		return err
+	}
+	return c.templateStatus(status, t, execName, data)
+}
+
+func (c *Context) templateStatus(status int, t *template.Template, name string, data any) error {
+	return c.Render(status, &render.HTML{
 		Data:       data,
 		IsTemplate: true,
-		Template:   c.engine.HTMLRender.Template,
+		Template:   t,
 		Name:       name,
 	})
 }
@@ -246,6 +334,37 @@ func (c *Context) XML(status int, data any) error {
 	})
 }
 
+// PureJSON 不转义 HTML 字符 json 中需要原样输出 <a> 之类的内容时使用
+func (c *Context) PureJSON(status int, data any) error {
+	return c.Render(status, &render.PureJSON{Data: data})
+}
+
+func (c *Context) IndentedJSON(status int, data any) error {
+	return c.Render(status, &render.IndentedJSON{Data: data})
+}
+
+// JSONP 回调函数名取自查询参数 callback 没有或者不合法时按 JSON 输出
+func (c *Context) JSONP(status int, data any) error {
+	callback := c.GetQuery("callback")
+	if !render.ValidCallback(callback) {
+		return c.JSON(status, data)
+	}
+	return c.Render(status, &render.JSONP{Callback: callback, Data: data})
+}
+
+func (c *Context) YAML(status int, data any) error {
+	return c.Render(status, &render.YAML{Data: data})
+}
+
+// ProtoBuf data 必须是 proto.Message
+func (c *Context) ProtoBuf(status int, data any) error {
+	return c.Render(status, &render.ProtoBuf{Data: data})
+}
+
+func (c *Context) MsgPack(status int, data any) error {
+	return c.Render(status, &render.MsgPack{Data: data})
+}
+
 func (c *Context) File(fileName string) {
 	http.ServeFile(c.W, c.R, fileName)
 }
@@ -285,34 +404,170 @@ func (c *Context) String(status int, format string, values ...any) error {
 func (c *Context) Render(statusCode int, r render.Render) error {
 	//如果设置了statusCode，对header的修改就不生效了
 	err := r.Render(c.W, statusCode)
-	c.StatusCode = statusCode
+	//渲染失败时可能还没有写出 记录实际写出的状态码
+	if c.writer.Written() {
+		c.StatusCode = c.writer.Status()
+	}
 	//多次调用 WriteHeader 就会产生这样的警告 superfluous response.WriteHeader
 	return err
 }
 
+// SSE 发送一条 Server-Sent Events 并立即刷新 客户端断开后返回 ctx.R.Context().Err()
+func (c *Context) SSE(event string, data any) error {
+	return c.SSEvent(&render.SSEvent{Event: event, Data: data})
+}
+
+func (c *Context) SSEvent(event *render.SSEvent) error {
+	if err := c.R.Context().Err(); err != nil {
+		return err
+	}
+	if err := c.Render(http.StatusOK, event); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return nil
+}
+
+// Stream 循环调用 step 写数据 每次调用后刷新
+// step 返回 false 或者客户端断开时结束 客户端断开时返回 true
+// step 中等待数据时也应该监听 ctx.R.Context().Done()
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.R.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+		}
+		keepOpen := step(c.W)
+		c.writer.Flush()
+		if !keepOpen {
+			return false
+		}
+	}
+}
+
 func (c *Context) BindJson(obj any) error {
+	//binding.JSON 是值类型 这里修改的是副本
 	json := binding.JSON
 	json.DisallowUnknownFields = true
 	json.IsValidate = true
 	return c.MustBindWith(obj, json)
 }
 
+// Bind 根据请求方法和 Content-Type 选择绑定器 路由参数使用 uri 标签一起绑定
+func (c *Context) Bind(obj any) error {
+	if err := c.ShouldBindAuto(obj); err != nil {
+		c.bindError(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Context) ShouldBindAuto(obj any) error {
+	if len(c.params) > 0 {
+		if err := binding.MapUri(c.paramsMap(), obj); err != nil {
+			return err
+		}
+	}
+	bind := binding.Default(c.R.Method, c.ContentType())
+	if bind == binding.JSON {
+		json := binding.JSON
+		json.DisallowUnknownFields = c.DisallowUnknownFields
+		json.IsValidate = c.IsValidate
+		bind = json
+	}
+	return c.ShouldBind(obj, bind)
+}
+
+func (c *Context) BindUri(obj any) error {
+	if err := c.ShouldBindUri(obj); err != nil {
+		c.bindError(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Context) ShouldBindUri(obj any) error {
+	return binding.URI.BindUri(c.paramsMap(), obj)
+}
+
+func (c *Context) paramsMap() map[string][]string {
+	m := make(map[string][]string, len(c.params))
+	for _, p := range c.params {
+		m[p.Key] = []string{p.Value}
+	}
+	return m
+}
+
+func (c *Context) ContentType() string {
+	return c.GetHeader("Content-Type")
+}
+
 func (c *Context) BindXML(obj any) error {
 	return c.MustBindWith(obj, binding.XML)
 }
 
+func (c *Context) BindYAML(obj any) error {
+	return c.MustBindWith(obj, binding.YAML)
+}
+
+func (c *Context) BindProtoBuf(obj any) error {
+	return c.MustBindWith(obj, binding.ProtoBuf)
+}
+
+func (c *Context) BindMsgPack(obj any) error {
+	return c.MustBindWith(obj, binding.MsgPack)
+}
+
 func (c *Context) MustBindWith(obj any, bind binding.Binding) error {
 	if err := c.ShouldBind(obj, bind); err != nil {
-		c.W.WriteHeader(http.StatusBadRequest)
+		c.bindError(err)
 		return err
 	}
 	return nil
 }
 
 func (c *Context) ShouldBind(obj any, bind binding.Binding) error {
+	//先按 Upload 设置的 MaxMemory 解析 绑定器中不会再重复解析
+	if bind == binding.Form || bind == binding.FormMultipart {
+		if err := c.R.ParseMultipartForm(c.maxMemory()); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return c.bodyError(err)
+		}
+	}
 	return bind.Bind(c.R, obj)
 }
 
+// bindError 绑定失败时返回 400 校验错误按 Accept-Language 翻译并列出每个字段
+// {"code":400,"msg":"...","errors":[{"field":"items[0].name","rule":"required","message":"name为必填字段"}]}
+// 请求体超过 BodyLimit 时返回 413
+func (c *Context) bindError(err error) {
+	if errors.Is(err, ErrBodyTooLarge) {
+		_ = c.JSON(http.StatusRequestEntityTooLarge, map[string]any{"code": http.StatusRequestEntityTooLarge, "msg": err.Error()})
+		return
+	}
+	var validationError binding.ValidationError
+	if !errors.As(err, &validationError) {
+		_ = c.JSON(http.StatusBadRequest, map[string]any{"code": http.StatusBadRequest, "msg": err.Error()})
+		return
+	}
+	errs := validationError.Translate(c.locale())
+	_ = c.JSON(http.StatusBadRequest, map[string]any{
+		"code":   http.StatusBadRequest,
+		"msg":    errs.Error(),
+		"errors": errs,
+	})
+}
+
+// locale Accept-Language 中的第一个语言 zh-CN,zh;q=0.9 -> zh-CN
+func (c *Context) locale() string {
+	lang := c.GetHeader("Accept-Language")
+	if i := strings.IndexAny(lang, ",;"); i >= 0 {
+		lang = lang[:i]
+	}
+	return strings.TrimSpace(lang)
+}
+
 func (c *Context) Fail(code int, msg string) {
 	c.String(code, msg)
 }
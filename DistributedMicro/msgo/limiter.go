@@ -16,6 +16,7 @@ func Limiter(limit, cap int) MiddlewareFunc {
 			defer cancel()
 			err := li.WaitN(con, 1)
 			if err != nil {
+				ctx.Abort()
 				ctx.String(http.StatusForbidden, "限流了")
 				return
 			}
@@ -1,11 +1,15 @@
 package msgo
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -30,10 +34,38 @@ const (
 
 var DefaultWriter io.Writer = os.Stdout
 
+// 访问日志的格式
+const (
+	LogFormatText     = "text"
+	LogFormatJSON     = "json"
+	LogFormatCombined = "combined"
+)
+
+// 可以选择输出的字段 时间 状态码 耗时 客户端 ip 方法和路径总是输出
+const (
+	LogFieldSize      = "size"
+	LogFieldUserAgent = "user_agent"
+	LogFieldReferer   = "referer"
+	LogFieldUser      = "user"
+	LogFieldRequestID = "request_id"
+)
+
 type LoggingConfig struct {
+	//自定义格式 设置后忽略 Format 和 Fields
 	Formatter LoggerFormatter
-	out       io.Writer
-	IsColor   bool
+	//text json 或 combined(Apache combined 格式) 默认 text
+	Format string
+	//额外输出的字段 如 LogFieldSize LogFieldUser combined 格式固定包含大小 referer 和 user agent
+	Fields []string
+	//为空时写到 DefaultWriter 并带颜色 可以是文件或 msLog.RotateWriter
+	Out     io.Writer
+	IsColor bool
+	//不记录的路径 如健康检查 /health
+	SkipPaths []string
+	//记录的比例 0 到 1 之间 0 表示全部记录 状态码 >= 500 的请求总是记录
+	SampleRate float64
+	//可信的代理 ip 或网段 如 10.0.0.0/8 请求来自这些地址时才使用 X-Forwarded-For 和 X-Real-IP
+	TrustedProxies []string
 }
 
 type LoggerFormatter = func(params *LogFormatterParams) string
@@ -47,6 +79,15 @@ type LogFormatterParams struct {
 	Method         string
 	Path           string
 	IsDisplayColor bool
+	//响应体的字节数
+	Size      int
+	UserAgent string
+	Referer   string
+	//ctx.Get("user") 的值
+	User      string
+	RequestID string
+	//需要输出的额外字段
+	Fields []string
 }
 
 func (p *LogFormatterParams) StatusCodeColor() string {
@@ -79,7 +120,7 @@ var defaultFormatter = func(params *LogFormatterParams) string {
 			cyan, params.Path, resetColor,
 		)
 	}
-	return fmt.Sprintf("[msgo] %v | %3d | %13v | %15s |%-7s %#v",
+	return fmt.Sprintf("[msgo] %v | %3d | %13v | %15s |%-7s %#v\n",
 		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
 		params.StatusCode,
 		params.Latency, params.ClientIP, params.Method, params.Path,
@@ -87,34 +128,86 @@ var defaultFormatter = func(params *LogFormatterParams) string {
 
 }
 
+// NewLogging 按配置创建访问日志中间件 Format 或 TrustedProxies 不合法时返回错误
+func NewLogging(conf LoggingConfig) (MiddlewareFunc, error) {
+	formatter, err := logFormatter(conf)
+	if err != nil {
+		return nil, err
+	}
+	trusted, err := parseTrustedProxies(conf.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return logging(conf, formatter, trusted, next)
+	}, nil
+}
+
+// LoggingWithConfig 配置不合法时打印错误 使用 text 格式并且不信任任何代理 需要检查错误时使用 NewLogging
 func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
-	formatter := conf.Formatter
-	if formatter == nil {
-		formatter = defaultFormatter
+	formatter, err := logFormatter(conf)
+	if err != nil {
+		log.Println(err)
+		formatter = textFormatter
+	}
+	trusted, err := parseTrustedProxies(conf.TrustedProxies)
+	if err != nil {
+		log.Println(err)
+		trusted = nil
+	}
+	return logging(conf, formatter, trusted, next)
+}
+
+func logFormatter(conf LoggingConfig) (LoggerFormatter, error) {
+	if conf.Formatter != nil {
+		return conf.Formatter, nil
+	}
+	switch conf.Format {
+	case LogFormatJSON:
+		return jsonFormatter, nil
+	case LogFormatCombined:
+		return combinedFormatter, nil
+	case "", LogFormatText:
+		return textFormatter, nil
 	}
-	out := conf.out
-	displayColor := false
+	return nil, fmt.Errorf("msgo: unknown log format %q", conf.Format)
+}
+
+func logging(conf LoggingConfig, formatter LoggerFormatter, trusted []*net.IPNet, next HandlerFunc) HandlerFunc {
+	out := conf.Out
+	displayColor := conf.IsColor
 	if out == nil {
 		out = DefaultWriter
 		displayColor = true
 	}
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = true
+	}
 	return func(ctx *Context) {
 		r := ctx.R
+		if skip[r.URL.Path] {
+			next(ctx)
+			return
+		}
 		param := &LogFormatterParams{
 			Request:        r,
 			IsDisplayColor: displayColor,
+			Fields:         conf.Fields,
 		}
 		// Start timer
 		start := time.Now()
 		path := r.URL.Path
 		raw := r.URL.RawQuery
 		next(ctx)
+		//直接写 ctx.W 的响应也能拿到真实的状态码
+		statusCode := ctx.Writer().Status()
+		if conf.SampleRate > 0 && conf.SampleRate < 1 && statusCode < http.StatusInternalServerError && rand.Float64() >= conf.SampleRate {
+			return
+		}
 		stop := time.Now()
 		latency := stop.Sub(start)
-		ip, _, _ := net.SplitHostPort(strings.TrimSpace(ctx.R.RemoteAddr))
-		clientIP := net.ParseIP(ip)
 		method := r.Method
-		statusCode := ctx.StatusCode
 
 		if raw != "" {
 			path = path + "?" + raw
@@ -124,12 +217,179 @@ func LoggingWithConfig(conf LoggingConfig, next HandlerFunc) HandlerFunc {
 		param.StatusCode = statusCode
 		param.Latency = latency
 		param.Path = path
-		param.ClientIP = clientIP
+		param.ClientIP = clientIP(r, trusted)
 		param.Method = method
+		param.Size = ctx.Writer().Size()
+		param.UserAgent = r.UserAgent()
+		param.Referer = r.Referer()
+		if user, ok := ctx.Get("user"); ok && user != nil {
+			param.User = fmt.Sprint(user)
+		}
+		//RequestID 中间件在内层时 ctx.R 已经替换
+		param.RequestID = ctx.RequestID()
 
-		fmt.Fprint(out, formatter(param))
+		//每条日志单独一行 自定义的格式也是
+		line := formatter(param)
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		if _, err := io.WriteString(out, line); err != nil {
+			log.Println(err)
+		}
 	}
 }
+
 func Logging(next HandlerFunc) HandlerFunc {
 	return LoggingWithConfig(LoggingConfig{}, next)
 }
+
+// textFormatter defaultFormatter 后面加上选择的字段
+func textFormatter(params *LogFormatterParams) string {
+	line := defaultFormatter(params)
+	if len(params.Fields) == 0 {
+		return line
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(line, " \n"))
+	for _, field := range params.Fields {
+		fmt.Fprintf(&sb, " | %s=%q", field, params.field(field))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func jsonFormatter(params *LogFormatterParams) string {
+	entry := map[string]any{
+		"time":       params.TimeStamp.Format(time.RFC3339Nano),
+		"status":     params.StatusCode,
+		"latency_ms": float64(params.Latency.Microseconds()) / 1000,
+		"client_ip":  ipString(params.ClientIP),
+		"method":     params.Method,
+		"path":       params.Path,
+	}
+	for _, field := range params.Fields {
+		if field == LogFieldSize {
+			entry[field] = params.Size
+			continue
+		}
+		entry[field] = params.field(field)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(line) + "\n"
+}
+
+// combinedFormatter Apache combined 格式
+// 127.0.0.1 - user [17/Oct/2026:20:30:00 +0800] "GET /path HTTP/1.1" 200 512 "referer" "user agent"
+func combinedFormatter(params *LogFormatterParams) string {
+	size := "-"
+	if params.Size > 0 {
+		size = strconv.Itoa(params.Size)
+	}
+	proto := "HTTP/1.1"
+	if params.Request != nil {
+		proto = params.Request.Proto
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		ipString(params.ClientIP), dash(params.User), params.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		params.Method, params.Path, proto, params.StatusCode, size,
+		dash(params.Referer), dash(params.UserAgent),
+	)
+}
+
+func (p *LogFormatterParams) field(name string) string {
+	switch name {
+	case LogFieldSize:
+		return strconv.Itoa(p.Size)
+	case LogFieldUserAgent:
+		return p.UserAgent
+	case LogFieldReferer:
+		return p.Referer
+	case LogFieldUser:
+		return p.User
+	case LogFieldRequestID:
+		return p.RequestID
+	}
+	return ""
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return "-"
+	}
+	return ip.String()
+}
+
+// parseTrustedProxies 单个 ip 转成 /32 或 /128 的网段
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("msgo: invalid trusted proxy %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("msgo: invalid trusted proxy %q: %w", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP 直接连接的地址不是可信代理时 不信任任何请求头
+// 否则从右向左查找 X-Forwarded-For 中第一个不可信的地址 都可信时使用最左边的
+func clientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(r.RemoteAddr)
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !isTrusted(remote, trusted) {
+		return remote
+	}
+	var forwarded []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(value, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			//格式错误的地址之前的内容都不可信
+			return remote
+		}
+		if !isTrusted(ip, trusted) || i == 0 {
+			return ip
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip
+	}
+	return remote
+}
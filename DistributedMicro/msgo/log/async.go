@@ -0,0 +1,179 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy 缓冲区满时的处理方式
+type OverflowPolicy int
+
+const (
+	//等待后台协程写出 不丢日志
+	OverflowBlock OverflowPolicy = iota
+	//丢弃新写入的日志
+	OverflowDropNewest
+	//丢弃缓冲区中最早的日志
+	OverflowDropOldest
+)
+
+// ParseOverflowPolicy 配置文件中使用 block drop drop_oldest
+func ParseOverflowPolicy(policy string) (OverflowPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "", "block":
+		return OverflowBlock, nil
+	case "drop", "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	}
+	return OverflowBlock, fmt.Errorf("log: unknown overflow policy %q", policy)
+}
+
+type AsyncConfig struct {
+	//缓冲的日志条数 默认 1024
+	BufferSize int
+	//一次最多合并写出的条数 默认 128
+	BatchSize int
+	Overflow  OverflowPolicy
+}
+
+// AsyncWriter 把日志放入环形缓冲区后立即返回 由后台协程合并后写入 Out
+// Close 和 Flush 会等待缓冲区中的日志写完
+type AsyncWriter struct {
+	out     io.Writer
+	conf    AsyncConfig
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ring    [][]byte
+	head    int
+	count   int
+	writing bool
+	closed  bool
+	dropped int64
+	done    chan struct{}
+}
+
+func NewAsyncWriter(out io.Writer, conf AsyncConfig) *AsyncWriter {
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1024
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 128
+	}
+	w := &AsyncWriter{
+		out:  out,
+		conf: conf,
+		ring: make([][]byte, conf.BufferSize),
+		done: make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.loop()
+	return w
+}
+
+// Write p 会被复制 调用方可以复用
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	entry := append([]byte(nil), p...)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.closed && w.count == len(w.ring) && w.conf.Overflow == OverflowBlock {
+		w.cond.Wait()
+	}
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.count == len(w.ring) {
+		atomic.AddInt64(&w.dropped, 1)
+		if w.conf.Overflow == OverflowDropNewest {
+			return len(p), nil
+		}
+		w.ring[w.head] = nil
+		w.head = (w.head + 1) % len(w.ring)
+		w.count--
+	}
+	w.ring[(w.head+w.count)%len(w.ring)] = entry
+	w.count++
+	w.cond.Broadcast()
+	return len(p), nil
+}
+
+// Dropped 缓冲区满时丢弃的日志条数
+func (w *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Flush 等待已经写入的日志全部写出
+func (w *AsyncWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+	return nil
+}
+
+// Close 写完缓冲区中的日志后关闭 Out 标准输出不关闭
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+	if w.out == os.Stdout || w.out == os.Stderr {
+		return nil
+	}
+	if c, ok := w.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Unwrap 返回实际写入的 Writer
+func (w *AsyncWriter) Unwrap() io.Writer {
+	return w.out
+}
+
+func (w *AsyncWriter) loop() {
+	defer close(w.done)
+	var batch bytes.Buffer
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 && w.closed {
+			w.mu.Unlock()
+			return
+		}
+		//一次取出多条 合并成一次写入
+		for n := 0; n < w.conf.BatchSize && w.count > 0; n++ {
+			batch.Write(w.ring[w.head])
+			w.ring[w.head] = nil
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+		}
+		w.writing = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		if _, err := w.out.Write(batch.Bytes()); err != nil {
+			os.Stderr.WriteString("log: async write: " + err.Error() + "\n")
+		}
+		batch.Reset()
+
+		w.mu.Lock()
+		w.writing = false
+		w.cond.Broadcast()
+		w.mu.Unlock()
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingWriter 在 release 之前阻塞写入 用来填满缓冲区
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	writes  int
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}, 1), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	tests := []struct {
+		policy  OverflowPolicy
+		want    string
+		dropped int64
+	}{
+		{OverflowDropNewest, "0\n1\n2\n", 2},
+		{OverflowDropOldest, "0\n3\n4\n", 2},
+		{OverflowBlock, "0\n1\n2\n3\n4\n", 0},
+	}
+	for _, tt := range tests {
+		out := newBlockingWriter()
+		w := NewAsyncWriter(out, AsyncConfig{BufferSize: 2, Overflow: tt.policy})
+		w.Write([]byte("0\n"))
+		//后台协程取走第一条后阻塞在写入上
+		<-out.started
+		done := make(chan struct{})
+		go func() {
+			for _, s := range []string{"1\n", "2\n", "3\n", "4\n"} {
+				w.Write([]byte(s))
+			}
+			close(done)
+		}()
+		if tt.policy != OverflowBlock {
+			<-done
+		}
+		close(out.release)
+		<-done
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if out.buf.String() != tt.want || w.Dropped() != tt.dropped {
+			t.Errorf("policy %d: got %q dropped %d, want %q dropped %d", tt.policy, out.buf.String(), w.Dropped(), tt.want, tt.dropped)
+		}
+	}
+}
+
+func TestAsyncLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New()
+	logger.Formatter = &JsonFormatter{}
+	logger.Outs = append(logger.Outs, &LoggerWriter{Level: LevelAll, Out: &buf})
+	logger.Async(AsyncConfig{BufferSize: 16})
+	child := logger.With("request_id", "abc")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				child.Infof("line %d", j)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := logger.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "request_id"); n != 400 {
+		t.Errorf("flushed %d lines, want 400", n)
+	}
+	logger.Close()
+	if _, err := logger.Outs[0].Out.Write([]byte("x")); err == nil {
+		t.Error("write after close should fail")
+	}
+}
@@ -0,0 +1,28 @@
+package log
+
+import "context"
+
+type fieldsKey struct{}
+
+// ContextWithFields 把 request_id trace_id 等字段放入 ctx 和 ctx 中已有的字段合并
+// 拿到 ctx 的地方使用 Logger.WithContext 打印日志
+func ContextWithFields(ctx context.Context, fields Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, mergeFields(FieldsFromContext(ctx), fields))
+}
+
+func FieldsFromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).(Fields)
+	return fields
+}
+
+// WithContext 返回带有 ctx 中字段的子 logger ctx 中没有字段时返回 l
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields := FieldsFromContext(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.WithFields(fields)
+}
@@ -11,17 +11,22 @@ type JsonFormatter struct {
 }
 
 func (f *JsonFormatter) Format(param *LoggingFormatParam) string {
-	if param.LoggerFields == nil {
-		param.LoggerFields = make(Fields)
+	//复制一份 不修改 logger 上的字段
+	fields := make(Fields, len(param.LoggerFields)+3)
+	for k, v := range param.LoggerFields {
+		fields[k] = v
 	}
-
 	now := time.Now()
 	if f.TimeDisplay {
-		param.LoggerFields["log_time"] = now.Format("2006/01/02 - 15:04:05")
+		fields["log_time"] = now.Format("2006/01/02 - 15:04:05")
+	}
+	if err, ok := param.Msg.(error); ok {
+		fields["msg"] = err.Error()
+	} else {
+		fields["msg"] = param.Msg
 	}
-	param.LoggerFields["msg"] = param.Msg
-	param.LoggerFields["log_level"] = param.Level.Level()
-	marshal, err := json.Marshal(param.LoggerFields)
+	fields["log_level"] = param.Level.Level()
+	marshal, err := json.Marshal(fields)
 	if err != nil {
 		panic(err)
 	}
@@ -2,7 +2,6 @@ package log
 
 import (
 	"fmt"
-	"github.com/mszlu521/msgo/internal/msstrings"
 	"io"
 	"log"
 	"os"
@@ -34,23 +33,90 @@ type LoggerLevel int
 
 func (l LoggerLevel) Level() string {
 	switch l {
+	case LevelTrace:
+		return "TRACE"
 	case LevelDebug:
 		return "DEBUG"
 	case LevelInfo:
 		return "INFO"
+	case LevelWarn:
+		return "WARN"
 	case LevelError:
 		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
 	default:
 		return ""
 	}
 }
 
+func (l LoggerLevel) String() string {
+	return l.Level()
+}
+
+// UnmarshalText 配置文件中可以直接写级别的名字 level="warn"
+func (l *LoggerLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// 保持原有级别的值不变 新增的级别放在后面 比较级别高低使用 severity
 const (
 	LevelDebug LoggerLevel = iota
 	LevelInfo
 	LevelError
+	LevelTrace
+	LevelWarn
+	LevelFatal
 )
 
+// severity 级别从低到高 Trace Debug Info Warn Error Fatal
+func (l LoggerLevel) severity() int {
+	switch l {
+	case LevelTrace:
+		return 0
+	case LevelDebug:
+		return 1
+	case LevelInfo:
+		return 2
+	case LevelWarn:
+		return 3
+	case LevelError:
+		return 4
+	case LevelFatal:
+		return 5
+	}
+	return -1
+}
+
+// LevelAll LoggerWriter 使用时写入所有级别的日志
+const LevelAll LoggerLevel = -1
+
+// ParseLevel 忽略大小写 warning 和 warn 相同
+func ParseLevel(level string) (LoggerLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "fatal":
+		return LevelFatal, nil
+	}
+	return LevelInfo, fmt.Errorf("log: unknown level %q", level)
+}
+
+const defaultLogFileSize = 100 << 20
+
 type Fields map[string]any
 
 // Logger 日志
@@ -60,7 +126,10 @@ type Logger struct {
 	Outs         []*LoggerWriter
 	LoggerFields Fields
 	logPath      string
-	LogFileSize  int64
+	//超过这个大小时切割 SetLogPath 之后修改也会生效 默认 100M
+	LogFileSize int64
+	//SetLogPath 创建的文件使用的切割配置 Filename 不需要设置
+	Rotate RotateConfig
 }
 
 type LoggerWriter struct {
@@ -85,15 +154,16 @@ type LoggerFormatter struct {
 	LoggerFields Fields
 }
 
+// New 默认级别是 LevelDebug 不输出 Trace
 func New() *Logger {
-	return &Logger{}
+	return &Logger{Level: LevelDebug}
 }
 
 func Default() *Logger {
 	logger := New()
 	logger.Level = LevelDebug
 	w := &LoggerWriter{
-		Level: LevelDebug,
+		Level: LevelAll,
 		Out:   os.Stdout,
 	}
 	logger.Outs = append(logger.Outs, w)
@@ -101,93 +171,302 @@ func Default() *Logger {
 	return logger
 }
 
-func (l *Logger) Info(msg any) {
-	l.Print(LevelInfo, msg)
+func (l *Logger) Trace(msg any) {
+	l.Print(LevelTrace, msg)
 }
 
 func (l *Logger) Debug(msg any) {
 	l.Print(LevelDebug, msg)
 }
 
+func (l *Logger) Info(msg any) {
+	l.Print(LevelInfo, msg)
+}
+
+func (l *Logger) Warn(msg any) {
+	l.Print(LevelWarn, msg)
+}
+
 func (l *Logger) Error(msg any) {
 	l.Print(LevelError, msg)
 }
 
+// Fatal 写完日志后关闭文件并退出程序
+func (l *Logger) Fatal(msg any) {
+	l.Print(LevelFatal, msg)
+	l.exit()
+}
+
+func (l *Logger) Tracef(format string, args ...any) {
+	l.printf(LevelTrace, format, args)
+}
+
+func (l *Logger) Debugf(format string, args ...any) {
+	l.printf(LevelDebug, format, args)
+}
+
+func (l *Logger) Infof(format string, args ...any) {
+	l.printf(LevelInfo, format, args)
+}
+
+func (l *Logger) Warnf(format string, args ...any) {
+	l.printf(LevelWarn, format, args)
+}
+
+func (l *Logger) Errorf(format string, args ...any) {
+	l.printf(LevelError, format, args)
+}
+
+func (l *Logger) Fatalf(format string, args ...any) {
+	l.printf(LevelFatal, format, args)
+	l.exit()
+}
+
+// Tracew Infow 等方法的键值对只加到这一条日志上 l.Infow("login", "user", name, "ip", ip)
+func (l *Logger) Tracew(msg string, keysAndValues ...any) {
+	l.printw(LevelTrace, msg, keysAndValues)
+}
+
+func (l *Logger) Debugw(msg string, keysAndValues ...any) {
+	l.printw(LevelDebug, msg, keysAndValues)
+}
+
+func (l *Logger) Infow(msg string, keysAndValues ...any) {
+	l.printw(LevelInfo, msg, keysAndValues)
+}
+
+func (l *Logger) Warnw(msg string, keysAndValues ...any) {
+	l.printw(LevelWarn, msg, keysAndValues)
+}
+
+func (l *Logger) Errorw(msg string, keysAndValues ...any) {
+	l.printw(LevelError, msg, keysAndValues)
+}
+
+func (l *Logger) Fatalw(msg string, keysAndValues ...any) {
+	l.printw(LevelFatal, msg, keysAndValues)
+	l.exit()
+}
+
+// Enabled 级别低于 Level 的日志不会输出 可以避免构造日志内容的开销
+func (l *Logger) Enabled(level LoggerLevel) bool {
+	return level.severity() >= l.Level.severity()
+}
+
 func (l *Logger) Print(level LoggerLevel, msg any) {
-	if l.Level > level {
-		//当前的级别大于输入级别 不打印对应的级别日志
+	l.print(level, msg, l.LoggerFields)
+}
+
+func (l *Logger) printf(level LoggerLevel, format string, args []any) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.print(level, fmt.Sprintf(format, args...), l.LoggerFields)
+}
+
+func (l *Logger) printw(level LoggerLevel, msg string, keysAndValues []any) {
+	if !l.Enabled(level) {
 		return
 	}
-	param := &LoggingFormatParam{
-		Level:        level,
-		LoggerFields: l.LoggerFields,
-		Msg:          msg,
+	l.print(level, msg, mergeFields(l.LoggerFields, keyValues(keysAndValues)))
+}
+
+// print 每种格式只格式化一次 每个输出只写一次
+// 标准输出和标准错误带颜色 其他输出按 LoggerWriter.Level 过滤
+func (l *Logger) print(level LoggerLevel, msg any, fields Fields) {
+	if !l.Enabled(level) {
+		//当前的级别大于输入级别 不打印对应的级别日志
+		return
 	}
-	str := l.Formatter.Format(param)
+	var plain, color string
 	for _, out := range l.Outs {
-		if out.Out == os.Stdout {
-			param.IsColor = true
-			str = l.Formatter.Format(param)
-			fmt.Fprintln(out.Out, str)
+		if out.Level != LevelAll && level != out.Level {
+			continue
+		}
+		isColor := isStd(out.Out)
+		str := &plain
+		if isColor {
+			str = &color
+		}
+		if *str == "" {
+			*str = l.Formatter.Format(&LoggingFormatParam{
+				Level:        level,
+				IsColor:      isColor,
+				LoggerFields: fields,
+				Msg:          msg,
+			})
 		}
-		if out.Level == -1 || level == out.Level {
-			fmt.Fprintln(out.Out, str)
+		fmt.Fprintln(out.Out, *str)
+		if !isColor {
 			l.CheckFileSize(out)
 		}
 	}
 }
 
+func (l *Logger) exit() {
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// WithFields 返回子 logger 包含父 logger 的字段 同名的字段使用新的值
 func (l *Logger) WithFields(fields Fields) *Logger {
 	return &Logger{
 		Formatter:    l.Formatter,
 		Outs:         l.Outs,
 		Level:        l.Level,
-		LoggerFields: fields,
+		LoggerFields: mergeFields(l.LoggerFields, fields),
+		logPath:      l.logPath,
+		LogFileSize:  l.LogFileSize,
+		Rotate:       l.Rotate,
+	}
+}
+
+// With 和 WithFields 相同 使用键值对 l.With("request_id", id)
+func (l *Logger) With(keysAndValues ...any) *Logger {
+	return l.WithFields(keyValues(keysAndValues))
+}
+
+func mergeFields(parent, fields Fields) Fields {
+	if len(fields) == 0 {
+		return parent
 	}
+	merged := make(Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+// keyValues 键不是字符串或者缺少值时 使用 !BADKEY 作为键 有多个时依次为 !BADKEY2 !BADKEY3 不会互相覆盖
+func keyValues(keysAndValues []any) Fields {
+	fields := make(Fields, len(keysAndValues)/2+1)
+	bad := 0
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			bad++
+			if bad == 1 {
+				fields["!BADKEY"] = keysAndValues[i]
+			} else {
+				fields[fmt.Sprintf("!BADKEY%d", bad)] = keysAndValues[i]
+			}
+			i--
+			continue
+		}
+		fields[key] = keysAndValues[i+1]
+	}
+	return fields
 }
 
+// SetLogPath 在目录下写 all.log 和每个级别的日志文件 按 Rotate 切割
 func (l *Logger) SetLogPath(logPath string) {
 	l.logPath = logPath
-	l.Outs = append(l.Outs, &LoggerWriter{
-		Level: -1,
-		Out:   FileWriter(path.Join(logPath, "all.log")),
-	})
-	l.Outs = append(l.Outs, &LoggerWriter{
-		Level: LevelDebug,
-		Out:   FileWriter(path.Join(logPath, "debug.log")),
-	})
-	l.Outs = append(l.Outs, &LoggerWriter{
-		Level: LevelInfo,
-		Out:   FileWriter(path.Join(logPath, "info.log")),
-	})
-	l.Outs = append(l.Outs, &LoggerWriter{
-		Level: LevelError,
-		Out:   FileWriter(path.Join(logPath, "error.log")),
-	})
+	for _, w := range []struct {
+		level LoggerLevel
+		name  string
+	}{
+		{LevelAll, "all.log"},
+		{LevelDebug, "debug.log"},
+		{LevelInfo, "info.log"},
+		{LevelWarn, "warn.log"},
+		{LevelError, "error.log"},
+	} {
+		l.Outs = append(l.Outs, &LoggerWriter{
+			Level: w.level,
+			Out:   l.rotateWriter(path.Join(logPath, w.name)),
+		})
+	}
 }
 
-func (l *Logger) CheckFileSize(w *LoggerWriter) {
-	//判断对应的文件大小
-	logFile := w.Out.(*os.File)
-	if logFile != nil {
-		stat, err := logFile.Stat()
-		if err != nil {
-			log.Println(err)
-			return
+func (l *Logger) rotateWriter(name string) *RotateWriter {
+	conf := l.Rotate
+	conf.Filename = name
+	if conf.MaxSize <= 0 && l.LogFileSize <= 0 {
+		conf.MaxSize = defaultLogFileSize
+	}
+	w, err := NewRotateWriter(conf)
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// Async 所有的输出改为异步写入 Close 时写完缓冲区中的日志
+func (l *Logger) Async(conf AsyncConfig) {
+	for _, out := range l.Outs {
+		if _, ok := out.Out.(*AsyncWriter); !ok {
+			out.Out = NewAsyncWriter(out.Out, conf)
 		}
-		size := stat.Size()
-		if l.LogFileSize <= 0 {
-			l.LogFileSize = 100 << 20
+	}
+}
+
+// Dropped 异步写入时因为缓冲区满丢弃的日志条数
+func (l *Logger) Dropped() int64 {
+	var dropped int64
+	for _, out := range l.Outs {
+		if w, ok := out.Out.(*AsyncWriter); ok {
+			dropped += w.Dropped()
 		}
-		if size >= l.LogFileSize {
-			_, name := path.Split(stat.Name())
-			fileName := name[0:strings.Index(name, ".")]
-			writer := FileWriter(path.Join(l.logPath, msstrings.JoinStrings(fileName, ".", time.Now().UnixMilli(), ".log")))
-			w.Out = writer
+	}
+	return dropped
+}
+
+// Flush 等待异步写入的日志写完
+func (l *Logger) Flush() error {
+	var err error
+	for _, out := range l.Outs {
+		if f, ok := out.Out.(interface{ Flush() error }); ok {
+			if e := f.Flush(); e != nil {
+				err = e
+			}
 		}
 	}
+	return err
+}
 
+// unwrap 异步写入时返回实际的输出
+func unwrap(w io.Writer) io.Writer {
+	if u, ok := w.(interface{ Unwrap() io.Writer }); ok {
+		return u.Unwrap()
+	}
+	return w
 }
+
+func isStd(w io.Writer) bool {
+	w = unwrap(w)
+	return w == os.Stdout || w == os.Stderr
+}
+
+// Close 关闭写入的日志文件 标准输出不关闭
+func (l *Logger) Close() error {
+	var err error
+	for _, out := range l.Outs {
+		if out.Out == os.Stdout || out.Out == os.Stderr {
+			continue
+		}
+		if c, ok := out.Out.(io.Closer); ok {
+			if e := c.Close(); e != nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// CheckFileSize 文件超过 LogFileSize 时切割 切割由 RotateWriter 完成 其他的输出忽略
+func (l *Logger) CheckFileSize(w *LoggerWriter) {
+	rw, ok := unwrap(w.Out).(*RotateWriter)
+	if !ok || l.LogFileSize <= 0 {
+		return
+	}
+	if err := rw.rotateIfLarger(l.LogFileSize); err != nil {
+		log.Println(err)
+	}
+}
+
 func (f *LoggerFormatter) format(msg any) string {
 	now := time.Now()
 	if f.IsColor {
@@ -210,7 +489,9 @@ func (f *LoggerFormatter) LevelColor() string {
 		return blue
 	case LevelInfo:
 		return green
-	case LevelError:
+	case LevelWarn:
+		return yellow
+	case LevelError, LevelFatal:
 		return red
 	default:
 		return cyan
@@ -219,7 +500,7 @@ func (f *LoggerFormatter) LevelColor() string {
 
 func (f *LoggerFormatter) MsgColor() string {
 	switch f.Level {
-	case LevelError:
+	case LevelError, LevelFatal:
 		return red
 	default:
 		return ""
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(level LoggerLevel) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := New()
+	logger.Level = level
+	logger.Formatter = &JsonFormatter{}
+	logger.Outs = append(logger.Outs, &LoggerWriter{Level: LevelAll, Out: &buf})
+	return logger, &buf
+}
+
+func lines(buf *bytes.Buffer) []map[string]any {
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		m := make(map[string]any)
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			panic(err)
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+func TestLevels(t *testing.T) {
+	logger, buf := newTestLogger(LevelWarn)
+	logger.Trace("trace")
+	logger.Debugf("debug %d", 1)
+	logger.Info("info")
+	logger.Warnf("warn %d", 2)
+	logger.Errorw("error", "code", 500)
+	got := lines(buf)
+	if len(got) != 2 {
+		t.Fatalf("got %d lines: %s", len(got), buf)
+	}
+	if got[0]["msg"] != "warn 2" || got[0]["log_level"] != "WARN" {
+		t.Errorf("warn line = %v", got[0])
+	}
+	if got[1]["msg"] != "error" || got[1]["log_level"] != "ERROR" || got[1]["code"] != float64(500) {
+		t.Errorf("error line = %v", got[1])
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	logger, buf := newTestLogger(LevelTrace)
+	logger.logPath = "./log"
+	logger.LogFileSize = 1 << 10
+	parent := logger.WithFields(Fields{"app": "msgo", "env": "dev"})
+	child := parent.With("env", "prod", "request_id", "abc")
+	if child.logPath != "./log" || child.LogFileSize != 1<<10 {
+		t.Errorf("child lost rotation settings: %q %d", child.logPath, child.LogFileSize)
+	}
+	child.Infow("hello", 1, "user", "ms", "dangling")
+	parent.Info("parent")
+	got := lines(buf)
+	want := map[string]any{"app": "msgo", "env": "prod", "request_id": "abc", "user": "ms", "!BADKEY": float64(1), "!BADKEY2": "dangling", "msg": "hello"}
+	for k, v := range want {
+		if got[0][k] != v {
+			t.Errorf("child %s = %v, want %v", k, got[0][k], v)
+		}
+	}
+	//子 logger 和单条日志的字段不影响父 logger
+	if got[1]["env"] != "dev" || got[1]["request_id"] != nil || got[1]["user"] != nil {
+		t.Errorf("parent line = %v", got[1])
+	}
+	if len(parent.LoggerFields) != 2 {
+		t.Errorf("parent fields = %v", parent.LoggerFields)
+	}
+}
+
+func TestWriteOncePerOutput(t *testing.T) {
+	var all, errOut bytes.Buffer
+	logger := New()
+	logger.Formatter = &TextFormatter{}
+	logger.Outs = []*LoggerWriter{{Level: LevelAll, Out: &all}, {Level: LevelError, Out: &errOut}}
+	logger.Info("info")
+	logger.Error("error")
+	if n := strings.Count(all.String(), "[msgo]"); n != 2 {
+		t.Errorf("all output has %d lines: %s", n, all.String())
+	}
+	if n := strings.Count(errOut.String(), "[msgo]"); n != 1 || !strings.Contains(errOut.String(), "error") {
+		t.Errorf("error output = %s", errOut.String())
+	}
+}
+
+func TestDefaultLevel(t *testing.T) {
+	//原有级别的值不能改变 配置中可能保存了数字
+	if LevelDebug != 0 || LevelInfo != 1 || LevelError != 2 {
+		t.Errorf("levels = %d %d %d", LevelDebug, LevelInfo, LevelError)
+	}
+	var buf bytes.Buffer
+	logger := New()
+	if logger.Level != LevelDebug {
+		t.Errorf("default level = %v", logger.Level)
+	}
+	logger.Formatter = &JsonFormatter{}
+	logger.Outs = append(logger.Outs, &LoggerWriter{Level: LevelAll, Out: &buf})
+	logger.Trace("trace")
+	logger.Debug("debug")
+	logger.Warn("warn")
+	got := lines(&buf)
+	if len(got) != 2 || got[0]["msg"] != "debug" || got[1]["msg"] != "warn" {
+		t.Errorf("lines = %v", got)
+	}
+	//级别的高低和数值无关
+	logger.Level = LevelWarn
+	if logger.Enabled(LevelInfo) || !logger.Enabled(LevelError) || !logger.Enabled(LevelFatal) {
+		t.Error("warn level should only enable warn and above")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for s, want := range map[string]LoggerLevel{"trace": LevelTrace, "DEBUG": LevelDebug, " info ": LevelInfo, "warning": LevelWarn, "Error": LevelError, "fatal": LevelFatal} {
+		got, err := ParseLevel(s)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) should fail")
+	}
+	var level LoggerLevel
+	if err := level.UnmarshalText([]byte("warn")); err != nil || level != LevelWarn {
+		t.Errorf("UnmarshalText = %v, %v", level, err)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	logger, buf := newTestLogger(LevelInfo)
+	if logger.WithContext(context.Background()) != logger {
+		t.Error("logger without context fields should be reused")
+	}
+	ctx := ContextWithFields(context.Background(), Fields{"request_id": "r1"})
+	ctx = ContextWithFields(ctx, Fields{"trace_id": "t1"})
+	logger.WithContext(ctx).Info("query")
+	got := lines(buf)
+	if got[0]["request_id"] != "r1" || got[0]["trace_id"] != "t1" {
+		t.Errorf("line = %v", got[0])
+	}
+}
@@ -0,0 +1,372 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateMode 按时间切割的周期
+type RotateMode int
+
+const (
+	//只按大小切割
+	RotateNone RotateMode = iota
+	RotateDaily
+	RotateHourly
+)
+
+// ParseRotateMode 配置文件中使用 daily hourly size
+func ParseRotateMode(mode string) (RotateMode, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "none", "size":
+		return RotateNone, nil
+	case "daily", "day":
+		return RotateDaily, nil
+	case "hourly", "hour":
+		return RotateHourly, nil
+	}
+	return RotateNone, fmt.Errorf("log: unknown rotate mode %q", mode)
+}
+
+// RotateConfig 日志文件的切割和保留
+type RotateConfig struct {
+	Filename string
+	Mode     RotateMode
+	//文件超过这个大小时切割 0 表示不按大小切割
+	MaxSize int64
+	//保留的旧文件个数 0 表示不限制
+	MaxBackups int
+	//保留的天数 0 表示不限制
+	MaxDays int
+	//切割后的旧文件用 gzip 压缩
+	Compress bool
+}
+
+// RotateWriter 写入 Filename 切割时把当前文件重命名为 all-2006-01-02.log 这样的名字
+// 按大小切割的文件名带有时间 all-2006-01-02T15-04-05.000.log
+// 压缩和删除旧文件在单独的协程中进行 不阻塞写日志 可以并发调用 Write
+type RotateWriter struct {
+	conf        RotateConfig
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	periodStart time.Time
+	closed      bool
+	now         func() time.Time
+	millCh      chan struct{}
+	millDone    chan struct{}
+}
+
+func NewRotateWriter(conf RotateConfig) (*RotateWriter, error) {
+	if conf.Filename == "" {
+		return nil, fmt.Errorf("log: rotate filename is empty")
+	}
+	w := &RotateWriter{
+		conf:     conf,
+		now:      time.Now,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+	go w.millLoop()
+	//启动时也清理一次之前留下的文件
+	w.mill()
+	return w, nil
+}
+
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.openExisting(); err != nil {
+			return 0, err
+		}
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即切割 如收到 SIGHUP 时
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// rotateIfLarger Logger.LogFileSize 可以在创建之后修改
+func (w *RotateWriter) rotateIfLarger(size int64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.file == nil || w.size < size {
+		return nil
+	}
+	return w.rotate()
+}
+
+// Close 关闭文件 等待正在进行的压缩和清理完成
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	close(w.millCh)
+	w.mu.Unlock()
+	<-w.millDone
+	return err
+}
+
+func (w *RotateWriter) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(w.conf.Filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.conf.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	//已有的内容属于文件最后修改的那个周期
+	if w.size > 0 {
+		w.periodStart = w.period(info.ModTime())
+	} else {
+		w.periodStart = w.period(w.now())
+	}
+	return nil
+}
+
+func (w *RotateWriter) shouldRotate(n int64) bool {
+	if w.conf.Mode != RotateNone && !w.period(w.now()).Equal(w.periodStart) {
+		return true
+	}
+	return w.conf.MaxSize > 0 && w.size > 0 && w.size+n > w.conf.MaxSize
+}
+
+func (w *RotateWriter) period(t time.Time) time.Time {
+	switch w.conf.Mode {
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// rotate 调用时持有锁 旧文件关闭后再重命名
+func (w *RotateWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	now := w.now()
+	if _, err := os.Stat(w.conf.Filename); err == nil {
+		if err := os.Rename(w.conf.Filename, w.backupName(now)); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(w.conf.Filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+	w.periodStart = w.period(now)
+	w.mill()
+	return nil
+}
+
+// backupName 跨周期切割时使用上一个周期的时间 同一个周期内按大小切割时使用当前时间
+func (w *RotateWriter) backupName(now time.Time) string {
+	base, ext := w.baseExt()
+	var stamp string
+	switch {
+	case w.conf.Mode == RotateDaily && !w.period(now).Equal(w.periodStart):
+		stamp = w.periodStart.Format("2006-01-02")
+	case w.conf.Mode == RotateHourly && !w.period(now).Equal(w.periodStart):
+		stamp = w.periodStart.Format("2006-01-02T15")
+	default:
+		stamp = now.Format("2006-01-02T15-04-05.000")
+	}
+	name := base + "-" + stamp + ext
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%s-%d%s", base, stamp, i, ext)
+	}
+	return name
+}
+
+func (w *RotateWriter) baseExt() (string, string) {
+	ext := filepath.Ext(w.conf.Filename)
+	return strings.TrimSuffix(w.conf.Filename, ext), ext
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// mill 通知协程压缩和清理 已经有等待的通知时不再重复
+func (w *RotateWriter) mill() {
+	if !w.conf.Compress && w.conf.MaxBackups <= 0 && w.conf.MaxDays <= 0 {
+		return
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+func (w *RotateWriter) millLoop() {
+	defer close(w.millDone)
+	for range w.millCh {
+		if err := w.millRun(); err != nil {
+			fmt.Fprintln(os.Stderr, "log: rotate:", err)
+		}
+	}
+}
+
+type backupFile struct {
+	path    string
+	modTime time.Time
+}
+
+func (w *RotateWriter) millRun() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+	var remove []backupFile
+	if w.conf.MaxBackups > 0 && len(backups) > w.conf.MaxBackups {
+		remove = append(remove, backups[w.conf.MaxBackups:]...)
+		backups = backups[:w.conf.MaxBackups]
+	}
+	if w.conf.MaxDays > 0 {
+		cutoff := w.now().Add(-time.Duration(w.conf.MaxDays) * 24 * time.Hour)
+		keep := backups[:0]
+		for _, b := range backups {
+			if b.modTime.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				keep = append(keep, b)
+			}
+		}
+		backups = keep
+	}
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !w.conf.Compress {
+		return nil
+	}
+	for _, b := range backups {
+		if strings.HasSuffix(b.path, ".gz") {
+			continue
+		}
+		if err := compressFile(b.path, b.modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backups 切割出的旧文件 最新的在前面
+func (w *RotateWriter) backups() ([]backupFile, error) {
+	base, ext := w.baseExt()
+	dir := filepath.Dir(w.conf.Filename)
+	prefix := filepath.Base(base) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].modTime.Equal(backups[j].modTime) {
+			return backups[i].modTime.After(backups[j].modTime)
+		}
+		return backups[i].path > backups[j].path
+	})
+	return backups, nil
+}
+
+// compressFile 压缩成功后删除原文件 保留原来的修改时间 按天数清理时使用
+func compressFile(name string, modTime time.Time) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(name + ".gz")
+		}
+	}()
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	_ = os.Chtimes(name+".gz", modTime, modTime)
+	src.Close()
+	return os.Remove(name)
+}
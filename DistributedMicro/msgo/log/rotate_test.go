@@ -0,0 +1,132 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func dirFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotateDaily(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 17, 23, 59, 0, 0, time.Local)
+	w, err := NewRotateWriter(RotateConfig{Filename: filepath.Join(dir, "all.log"), Mode: RotateDaily, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.now = func() time.Time { return now }
+	w.periodStart = w.period(now)
+	w.Write([]byte("day one\n"))
+	now = now.Add(2 * time.Minute)
+	w.Write([]byte("day two\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := dirFiles(t, dir); strings.Join(got, ",") != "all-2026-10-17.log.gz,all.log" {
+		t.Fatalf("files = %v", got)
+	}
+	f, _ := os.Open(filepath.Join(dir, "all-2026-10-17.log.gz"))
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(gz)
+	if string(content) != "day one\n" {
+		t.Errorf("backup = %q", content)
+	}
+	current, _ := os.ReadFile(filepath.Join(dir, "all.log"))
+	if string(current) != "day two\n" {
+		t.Errorf("current = %q", current)
+	}
+}
+
+func TestRotateSizeAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	//不属于这个文件的不能删除
+	os.WriteFile(filepath.Join(dir, "error-2020-01-01.log"), []byte("x"), 0644)
+	w, err := NewRotateWriter(RotateConfig{Filename: filepath.Join(dir, "info.log"), MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("0123456789"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := dirFiles(t, dir)
+	if len(got) != 4 || got[0] != "error-2020-01-01.log" || got[3] != "info.log" {
+		t.Errorf("files = %v", got)
+	}
+
+	//按天数清理
+	old := filepath.Join(dir, "info-2020-01-01.log")
+	os.WriteFile(old, []byte("old"), 0644)
+	past := time.Now().AddDate(0, 0, -10)
+	os.Chtimes(old, past, past)
+	w, err = NewRotateWriter(RotateConfig{Filename: filepath.Join(dir, "info.log"), MaxDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired backup not removed: %v", err)
+	}
+}
+
+func TestRotateConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	logger := New()
+	logger.Formatter = &JsonFormatter{}
+	logger.Rotate = RotateConfig{MaxSize: 1 << 10}
+	logger.SetLogPath(dir)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Infow("concurrent", "j", j)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for _, name := range dirFiles(t, dir) {
+		if !strings.HasPrefix(name, "all") {
+			continue
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, name))
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if !strings.HasSuffix(line, "}") {
+				t.Fatalf("broken line %q in %s", line, name)
+			}
+			lines++
+		}
+	}
+	if lines != 400 {
+		t.Errorf("lines = %d, want 400", lines)
+	}
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -13,21 +14,23 @@ func (f *TextFormatter) Format(param *LoggingFormatParam) string {
 	now := time.Now()
 	fieldsString := ""
 	if param.LoggerFields != nil {
-		//name=xx,age=xxx
+		//name=xx,age=xxx 按字段名排序 每次输出的顺序相同
+		keys := make([]string, 0, len(param.LoggerFields))
+		for k := range param.LoggerFields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
 		var sb strings.Builder
-		var count = 0
-		var lens = len(param.LoggerFields)
-		for k, v := range param.LoggerFields {
-			fmt.Fprintf(&sb, "%s=%v", k, v)
-			if count < lens-1 {
-				fmt.Fprintf(&sb, ",")
-				count++
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteString(",")
 			}
+			fmt.Fprintf(&sb, "%s=%v", k, param.LoggerFields[k])
 		}
 		fieldsString = sb.String()
 	}
 	var msgInfo = "\n msg: "
-	if param.Level == LevelError {
+	if param.Level.severity() >= LevelError.severity() {
 		msgInfo = "\n Error Cause By: "
 	}
 	if param.IsColor {
@@ -50,7 +53,9 @@ func (f *TextFormatter) LevelColor(level LoggerLevel) string {
 		return blue
 	case LevelInfo:
 		return green
-	case LevelError:
+	case LevelWarn:
+		return yellow
+	case LevelError, LevelFatal:
 		return red
 	default:
 		return cyan
@@ -59,7 +64,7 @@ func (f *TextFormatter) LevelColor(level LoggerLevel) string {
 
 func (f *TextFormatter) MsgColor(level LoggerLevel) string {
 	switch level {
-	case LevelError:
+	case LevelError, LevelFatal:
 		return red
 	default:
 		return ""
@@ -0,0 +1,157 @@
+package msgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func accessLogEngine(conf LoggingConfig) *Engine {
+	engine := New()
+	logging, err := NewLogging(conf)
+	if err != nil {
+		panic(err)
+	}
+	engine.Use(logging, RequestID)
+	g := engine.Group("")
+	g.Get("/user", func(ctx *Context) {
+		ctx.Set("user", "mszlu")
+		ctx.String(http.StatusOK, "hello")
+	})
+	g.Get("/health", func(ctx *Context) {
+		ctx.String(http.StatusOK, "ok")
+	})
+	g.Get("/fail", func(ctx *Context) {
+		ctx.String(http.StatusInternalServerError, "fail")
+	})
+	return engine
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	engine := accessLogEngine(LoggingConfig{
+		Format:         LogFormatJSON,
+		Fields:         []string{LogFieldSize, LogFieldUserAgent, LogFieldReferer, LogFieldUser, LogFieldRequestID},
+		Out:            &buf,
+		SkipPaths:      []string{"/health"},
+		TrustedProxies: []string{"10.0.0.0/8"},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/user?id=1", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	r.Header.Set("User-Agent", "msgo-test")
+	r.Header.Set("Referer", "http://example.com")
+	r.Header.Set(HeaderRequestID, "rid-1")
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if n := strings.Count(buf.String(), "\n"); n != 1 {
+		t.Fatalf("got %d lines: %s", n, buf.String())
+	}
+	entry := make(map[string]any)
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"status": float64(200), "client_ip": "1.2.3.4", "method": "GET", "path": "/user?id=1",
+		"size": float64(5), "user_agent": "msgo-test", "referer": "http://example.com", "user": "mszlu", "request_id": "rid-1",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	var buf bytes.Buffer
+	engine := accessLogEngine(LoggingConfig{Format: LogFormatCombined, Out: &buf})
+	r := httptest.NewRequest(http.MethodGet, "/user", nil)
+	r.RemoteAddr = "192.168.1.9:4000"
+	//不是可信代理 忽略 X-Forwarded-For
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	r.Header.Set("User-Agent", "curl/8.0")
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+	line := buf.String()
+	if !strings.HasPrefix(line, `192.168.1.9 - mszlu [`) || !strings.HasSuffix(line, `] "GET /user HTTP/1.1" 200 5 "-" "curl/8.0"`+"\n") {
+		t.Errorf("combined line = %q", line)
+	}
+}
+
+func TestAccessLogText(t *testing.T) {
+	var buf bytes.Buffer
+	engine := accessLogEngine(LoggingConfig{Out: &buf})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3 || lines[2] != "" || !strings.Contains(lines[0], "/user") || !strings.Contains(lines[1], "/fail") {
+		t.Errorf("text output = %q", buf.String())
+	}
+}
+
+func TestNewLoggingError(t *testing.T) {
+	if _, err := NewLogging(LoggingConfig{Format: "xml"}); err == nil {
+		t.Error("unknown format should fail")
+	}
+	if _, err := NewLogging(LoggingConfig{TrustedProxies: []string{"10.0.0.0/99"}}); err == nil {
+		t.Error("invalid trusted proxy should fail")
+	}
+	//LoggingWithConfig 不 panic 使用默认配置
+	var buf bytes.Buffer
+	handler := LoggingWithConfig(LoggingConfig{Format: "xml", Out: &buf}, func(ctx *Context) {})
+	engine := New()
+	engine.Group("").Get("/x", handler)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+	if !strings.HasSuffix(buf.String(), "\n") || !strings.Contains(buf.String(), "/x") {
+		t.Errorf("fallback output = %q", buf.String())
+	}
+}
+
+func TestAccessLogSample(t *testing.T) {
+	var buf bytes.Buffer
+	engine := accessLogEngine(LoggingConfig{Out: &buf, SampleRate: 0.000001, Fields: []string{LogFieldRequestID}})
+	for i := 0; i < 20; i++ {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user", nil))
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	//错误总是记录
+	if n := strings.Count(buf.String(), "\n"); n != 1 || !strings.Contains(buf.String(), "500") || !strings.Contains(buf.String(), "request_id=") {
+		t.Errorf("sampled output = %q", buf.String())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.0.1", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote, forwarded, realIP, want string
+	}{
+		{"8.8.8.8:1", "1.1.1.1", "", "8.8.8.8"},
+		{"10.1.1.1:1", "1.1.1.1, 2.2.2.2, 10.0.0.5", "", "2.2.2.2"},
+		{"10.1.1.1:1", "10.0.0.9, 10.0.0.5", "", "10.0.0.9"},
+		{"192.168.0.1:1", "", "3.3.3.3", "3.3.3.3"},
+		{"[::1]:1", "garbage, 4.4.4.4", "", "4.4.4.4"},
+		{"10.1.1.1:1", "garbage", "", "10.1.1.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := clientIP(r, trusted).String(); got != tt.want {
+			t.Errorf("remote %s forwarded %q: got %s, want %s", tt.remote, tt.forwarded, got, tt.want)
+		}
+	}
+	if _, err := parseTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("invalid proxy should fail")
+	}
+}
@@ -1,100 +1,213 @@
 package msgo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/mszlu521/msgo/config"
 	"github.com/mszlu521/msgo/gateway"
 	msLog "github.com/mszlu521/msgo/log"
 	"github.com/mszlu521/msgo/register"
 	"github.com/mszlu521/msgo/render"
+	"github.com/mszlu521/msgo/websocket"
 	"html/template"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 const ANY = "ANY"
 
+const defaultShutdownTimeout = 10 * time.Second
+
+const defaultReadHeaderTimeout = 10 * time.Second
+
 type HandlerFunc func(ctx *Context)
 
 type MiddlewareFunc func(handlerFunc HandlerFunc) HandlerFunc
 
 type routerGroup struct {
 	name               string
+	prefix             string
+	parent             *routerGroup
+	router             *router
 	handleFuncMap      map[string]map[string]HandlerFunc
+	handlers           map[string]map[string]HandlerFunc
 	middlewaresFuncMap map[string]map[string][]MiddlewareFunc
 	handlerMethodMap   map[string][]string
 	treeNode           *treeNode
 	middlewares        []MiddlewareFunc
+	//请求方法没有注册时的处理 冻结时和分组中间件组合
+	noMethod HandlerFunc
 }
 
 func (r *routerGroup) Use(middlewareFunc ...MiddlewareFunc) {
 	r.middlewares = append(r.middlewares, middlewareFunc...)
 }
 
-func (r *routerGroup) methodHandle(name string, method string, h HandlerFunc, ctx *Context) {
-	//组通用中间件
-	if r.middlewares != nil {
-		for _, middlewareFunc := range r.middlewares {
-			h = middlewareFunc(h)
+// Group 创建子分组 /api -> /api/v1，子分组继承父分组的前缀和中间件
+func (r *routerGroup) Group(name string) *routerGroup {
+	group := r.router.newGroup(name, joinPaths(r.prefix, name))
+	group.parent = r
+	return group
+}
+
+// groupMiddlewares 父分组的中间件在前 子分组的在后
+func (r *routerGroup) groupMiddlewares() []MiddlewareFunc {
+	var middlewares []MiddlewareFunc
+	if r.parent != nil {
+		middlewares = r.parent.groupMiddlewares()
+	}
+	return append(middlewares, r.middlewares...)
+}
+
+// allowMethods 路由上注册的请求方法 用于 405 和 OPTIONS 的 Allow 头
+func (r *routerGroup) allowMethods(name string) string {
+	methods := make([]string, 0, len(r.handleFuncMap[name])+2)
+	for method := range r.handleFuncMap[name] {
+		methods = append(methods, method)
+	}
+	if _, ok := r.handleFuncMap[name][http.MethodGet]; ok {
+		if _, ok := r.handleFuncMap[name][http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
 		}
 	}
-	//组路由级别
-	middlewareFuncs := r.middlewaresFuncMap[name][method]
-	if middlewareFuncs != nil {
-		for _, middlewareFunc := range middlewareFuncs {
-			h = middlewareFunc(h)
+	if _, ok := r.handleFuncMap[name][http.MethodOptions]; !ok {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// chainMiddlewares 引擎 -> 域名 -> 父分组 -> 子分组
+func (r *routerGroup) chainMiddlewares() []MiddlewareFunc {
+	middlewares := append([]MiddlewareFunc{}, r.router.engine.middles...)
+	middlewares = append(middlewares, r.router.middlewares...)
+	return append(middlewares, r.groupMiddlewares()...)
+}
+
+// routeMiddlewares 分组的中间件之后是路由级别的
+func (r *routerGroup) routeMiddlewares(name string, method string) []MiddlewareFunc {
+	return append(r.chainMiddlewares(), r.middlewaresFuncMap[name][method]...)
+}
+
+// compile 路由冻结时把中间件和处理函数组合好 请求过来直接调用
+func (r *routerGroup) compile(name string, method string) {
+	if r.handlers[name] == nil {
+		r.handlers[name] = make(map[string]HandlerFunc)
+	}
+	r.handlers[name][method] = chain(r.handleFuncMap[name][method], r.routeMiddlewares(name, method))
+}
+
+func (r *routerGroup) methodHandle(name string, method string, ctx *Context) {
+	r.handlers[name][method](ctx)
+}
+
+// chain 倒序包装 先添加的中间件在最外层 最先执行
+// 每一层 next 调用前检查 ctx.IsAborted() 中间件 Abort 之后即使调用了 next 也不会继续执行
+func chain(h HandlerFunc, middlewares []MiddlewareFunc) HandlerFunc {
+	h = abortable(h)
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = abortable(middlewares[i](h))
+	}
+	return h
+}
+
+func abortable(h HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		if ctx.IsAborted() {
+			return
 		}
+		h(ctx)
+	}
+}
+
+// match 请求路径以分组前缀开头时 返回去掉前缀后的路径 /api 不会匹配 /apix
+func (r *routerGroup) match(path string) (string, bool) {
+	if !strings.HasPrefix(path, r.prefix) {
+		return "", false
 	}
-	h(ctx)
+	rest := path[len(r.prefix):]
+	if rest != "" && rest[0] != '/' {
+		return "", false
+	}
+	return rest, true
 }
 
 //func (r *routerGroup) Add(Name string, handleFunc HandlerFunc) {
 //	r.handleFuncMap[Name] = handleFunc
 //}
 
-func (r *routerGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	_, ok := r.handleFuncMap[name]
+func (r *routerGroup) handle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) error {
+	//开始处理请求之后路由表只读 不加锁 不允许再注册
+	if r.router.engine.isFrozen() {
+		return fmt.Errorf("group [%s]: route [%s %s] registered after the engine started serving", r.prefix, method, name)
+	}
+	_, ok := r.handleFuncMap[name][method]
+	if ok {
+		return fmt.Errorf("group [%s]: route [%s %s] is already registered", r.prefix, method, name)
+	}
+	//先放入树中 有冲突直接返回 不记录处理函数
+	if err := r.treeNode.Put(name); err != nil {
+		return fmt.Errorf("group [%s]: %w", r.prefix, err)
+	}
+	_, ok = r.handleFuncMap[name]
 	if !ok {
 		r.handleFuncMap[name] = make(map[string]HandlerFunc)
 		r.middlewaresFuncMap[name] = make(map[string][]MiddlewareFunc)
 	}
-	_, ok = r.handleFuncMap[name][method]
-	if ok {
-		panic("有重复的路由")
-	}
 	r.handleFuncMap[name][method] = handlerFunc
 	r.middlewaresFuncMap[name][method] = append(r.middlewaresFuncMap[name][method], middlewareFunc...)
-	r.treeNode.Put(name)
+	return nil
+}
+
+// Handle 注册路由，路由重复或者和已有路由冲突时返回错误
+func (r *routerGroup) Handle(method string, name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) error {
+	return r.handle(name, method, handlerFunc, middlewareFunc...)
+}
+
+func (r *routerGroup) mustHandle(name string, method string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
+	if err := r.handle(name, method, handlerFunc, middlewareFunc...); err != nil {
+		panic(err)
+	}
 }
 
 func (r *routerGroup) Any(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, ANY, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, ANY, handlerFunc, middlewareFunc...)
 }
 
 func (r *routerGroup) Get(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodGet, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodGet, handlerFunc, middlewareFunc...)
 }
 func (r *routerGroup) Post(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPost, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodPost, handlerFunc, middlewareFunc...)
 }
 
 func (r *routerGroup) Delete(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodDelete, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodDelete, handlerFunc, middlewareFunc...)
 }
 func (r *routerGroup) Put(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPut, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodPut, handlerFunc, middlewareFunc...)
 }
 func (r *routerGroup) Patch(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodPatch, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodPatch, handlerFunc, middlewareFunc...)
 }
 func (r *routerGroup) Options(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodOptions, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodOptions, handlerFunc, middlewareFunc...)
 }
 func (r *routerGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc ...MiddlewareFunc) {
-	r.handle(name, http.MethodHead, handlerFunc, middlewareFunc...)
+	r.mustHandle(name, http.MethodHead, handlerFunc, middlewareFunc...)
 }
 
 //user  get->handle
@@ -103,18 +216,41 @@ func (r *routerGroup) Head(name string, handlerFunc HandlerFunc, middlewareFunc
 type router struct {
 	routerGroups []*routerGroup
 	engine       *Engine
+	host         string
+	middlewares  []MiddlewareFunc
+}
+
+// Use 域名级别的中间件 只对这个域名下的分组生效
+func (r *router) Use(middlewareFunc ...MiddlewareFunc) {
+	r.middlewares = append(r.middlewares, middlewareFunc...)
 }
 
 func (r *router) Group(name string) *routerGroup {
+	return r.newGroup(name, joinPaths("", name))
+}
+
+func (r *router) newGroup(name string, prefix string) *routerGroup {
 	routerGroup := &routerGroup{
 		name:               name,
+		prefix:             prefix,
+		router:             r,
 		handleFuncMap:      make(map[string]map[string]HandlerFunc),
+		handlers:           make(map[string]map[string]HandlerFunc),
 		middlewaresFuncMap: make(map[string]map[string][]MiddlewareFunc),
 		handlerMethodMap:   make(map[string][]string),
-		treeNode:           &treeNode{name: "/", children: make([]*treeNode, 0)},
+		treeNode:           &treeNode{},
 	}
-	routerGroup.Use(r.engine.middles...)
-	r.routerGroups = append(r.routerGroups, routerGroup)
+	//前缀长的分组排在前面 /api/v1 先于 /api 匹配
+	index := len(r.routerGroups)
+	for i, group := range r.routerGroups {
+		if len(group.prefix) < len(prefix) {
+			index = i
+			break
+		}
+	}
+	r.routerGroups = append(r.routerGroups, nil)
+	copy(r.routerGroups[index+1:], r.routerGroups[index:])
+	r.routerGroups[index] = routerGroup
 	return routerGroup
 }
 
@@ -135,6 +271,29 @@ type Engine struct {
 	RegisterType     string
 	RegisterOption   register.Option
 	RegisterCli      register.MsRegister
+	ShutdownTimeout  time.Duration
+	//http.Server 的超时 为 0 时使用配置文件 [server] 中的 read_header_timeout read_timeout write_timeout idle_timeout
+	//都没有配置时只设置 ReadHeaderTimeout 为 10s WriteTimeout 会限制 SSE 等长时间的响应
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	AutoRegister      bool //监听成功后 用 RegisterOption 中的 ServiceName/Host/Port 注册服务 关闭时摘除
+	WebSocketOptions  websocket.Options
+	TemplateDev       bool //开发模式 模板文件修改后重新解析
+	templates         sync.Map
+	hosts             []*router
+	noRoute           HandlerFunc
+	noMethod          HandlerFunc
+	allNoRoute        HandlerFunc
+	freezeOnce        sync.Once
+	startHooks        []func()
+	shutdownHooks     []func(ctx context.Context)
+	registerMu        sync.Mutex
+	//已经注册的服务 关闭时全部摘除
+	registered []registration
+	//开始处理请求后置为 1 注册路由时检查 使用 atomic 读写
+	frozen int32
 }
 
 func New() *Engine {
@@ -146,21 +305,74 @@ func New() *Engine {
 	engine.pool.New = func() any {
 		return engine.allocateContext()
 	}
+	engine.router.engine = engine
 	return engine
 }
 
 func Default() *Engine {
 	engine := New()
 	engine.Logger = msLog.Default()
+	engine.Logger.Rotate = logRotateConf(config.Conf.Log)
 	logPath, ok := config.Conf.Log["path"]
 	if ok {
 		engine.Logger.SetLogPath(logPath.(string))
 	}
+	//[log] async=true 异步写日志 buffer_size 缓冲的条数 overflow="block" "drop" 或 "drop_oldest"
+	if async, _ := config.Conf.Log["async"].(bool); async {
+		engine.Logger.Async(logAsyncConf(config.Conf.Log))
+	}
+	//[log] level="info"
+	if level, ok := config.Conf.Log["level"].(string); ok {
+		l, err := msLog.ParseLevel(level)
+		if err != nil {
+			engine.Logger.Error(err)
+		} else {
+			engine.Logger.Level = l
+		}
+	}
 	engine.Use(Logging, Recovery)
-	engine.router.engine = engine
 	return engine
 }
 
+// logRotateConf 读取 [log] 中的切割配置
+// rotate="daily" 或 "hourly" max_size 单位是 MB max_backups 保留的文件个数 max_days 保留的天数 compress=true 压缩旧文件
+func logRotateConf(conf map[string]any) msLog.RotateConfig {
+	var rotate msLog.RotateConfig
+	if mode, ok := conf["rotate"].(string); ok {
+		m, err := msLog.ParseRotateMode(mode)
+		if err != nil {
+			log.Println(err)
+		}
+		rotate.Mode = m
+	}
+	if size, ok := conf["max_size"].(int64); ok {
+		rotate.MaxSize = size << 20
+	}
+	if n, ok := conf["max_backups"].(int64); ok {
+		rotate.MaxBackups = int(n)
+	}
+	if n, ok := conf["max_days"].(int64); ok {
+		rotate.MaxDays = int(n)
+	}
+	rotate.Compress, _ = conf["compress"].(bool)
+	return rotate
+}
+
+func logAsyncConf(conf map[string]any) msLog.AsyncConfig {
+	var async msLog.AsyncConfig
+	if n, ok := conf["buffer_size"].(int64); ok {
+		async.BufferSize = int(n)
+	}
+	if policy, ok := conf["overflow"].(string); ok {
+		p, err := msLog.ParseOverflowPolicy(policy)
+		if err != nil {
+			log.Println(err)
+		}
+		async.Overflow = p
+	}
+	return async
+}
+
 func (e *Engine) allocateContext() any {
 	return &Context{engine: e}
 }
@@ -178,17 +390,87 @@ func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
 	e.funcMap = funcMap
 }
 
-func (e *Engine) LoadTemplate(pattern string) {
-	t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
-	e.SetHtmlTemplate(t)
+// LoadTemplate 按一个或多个 glob 加载本地模板 TemplateDev 为 true 时文件修改后自动重新解析
+func (e *Engine) LoadTemplate(patterns ...string) {
+	e.mustLoadTemplate(render.TemplateConfig{Patterns: patterns})
+}
+
+// LoadTemplateFS 从 fs.FS 加载模板 如 //go:embed 的 embed.FS
+func (e *Engine) LoadTemplateFS(fsys fs.FS, patterns ...string) {
+	e.mustLoadTemplate(render.TemplateConfig{FS: fsys, Patterns: patterns})
 }
 
+// LoadTemplateWithConfig 可以指定布局 FuncMap 为空时使用 SetFuncMap 设置的
+func (e *Engine) LoadTemplateWithConfig(conf render.TemplateConfig) error {
+	if conf.FuncMap == nil {
+		conf.FuncMap = e.funcMap
+	}
+	if e.TemplateDev {
+		conf.Dev = true
+	}
+	htmlRender, err := render.NewHTMLRender(conf)
+	if err != nil {
+		return err
+	}
+	e.HTMLRender = htmlRender
+	return nil
+}
+
+func (e *Engine) mustLoadTemplate(conf render.TemplateConfig) {
+	if err := e.LoadTemplateWithConfig(conf); err != nil {
+		panic(err)
+	}
+}
+
+// LoadTemplateConf 读取配置文件中的 [template]
+// pattern 和 layouts 可以是字符串或数组 layout 是布局模板名 dev 开启开发模式
 func (e *Engine) LoadTemplateConf() {
-	pattern, ok := config.Conf.Template["pattern"]
-	if ok {
-		t := template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern.(string)))
-		e.SetHtmlTemplate(t)
+	conf := config.Conf.Template
+	patterns := toStrings(conf["pattern"])
+	if len(patterns) == 0 {
+		return
+	}
+	layout, _ := conf["layout"].(string)
+	dev, _ := conf["dev"].(bool)
+	e.mustLoadTemplate(render.TemplateConfig{
+		Patterns: patterns,
+		Layouts:  toStrings(conf["layouts"]),
+		Layout:   layout,
+		Dev:      dev,
+	})
+}
+
+func toStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// parseTemplate HTMLTemplate 使用的模板 生产模式按文件缓存 只解析一次
+func (e *Engine) parseTemplate(key string, parse func(t *template.Template) (*template.Template, error)) (*template.Template, error) {
+	if !e.TemplateDev {
+		if t, ok := e.templates.Load(key); ok {
+			return t.(*template.Template), nil
+		}
+	}
+	t, err := parse(template.New("").Funcs(e.funcMap))
+	if err != nil {
+		return nil, err
+	}
+	if !e.TemplateDev {
+		e.templates.Store(key, t)
 	}
+	return t, nil
 }
 
 func (e *Engine) SetHtmlTemplate(t *template.Template) {
@@ -197,9 +479,9 @@ func (e *Engine) SetHtmlTemplate(t *template.Template) {
 
 func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := e.pool.Get().(*Context)
-	ctx.W = w
-	ctx.R = r
+	ctx.reset(w, r)
 	ctx.Logger = e.Logger
+	e.freeze()
 	e.httpRequestHandle(ctx, w, r)
 
 	e.pool.Put(ctx)
@@ -252,38 +534,245 @@ func (e *Engine) httpRequestHandle(ctx *Context, w http.ResponseWriter, r *http.
 		return
 	}
 	method := r.Method
-	for _, group := range e.routerGroups {
-		routerName := SubStringLast(r.URL.Path, "/"+group.name)
+	for _, group := range e.matchHost(r.Host).routerGroups {
+		routerName, ok := group.match(r.URL.Path)
+		if !ok {
+			continue
+		}
 		// get/1
-		node := group.treeNode.Get(routerName)
+		ctx.params = ctx.params[:0]
+		node := group.treeNode.Get(routerName, &ctx.params)
 		if node != nil && node.isEnd {
 			//路由匹配上了
-			handle, ok := group.handleFuncMap[node.routerName][ANY]
+			_, ok := group.handleFuncMap[node.routerName][ANY]
 			if ok {
-				group.methodHandle(node.routerName, ANY, handle, ctx)
+				group.methodHandle(node.routerName, ANY, ctx)
 				return
 			}
-			handle, ok = group.handleFuncMap[node.routerName][method]
+			_, ok = group.handleFuncMap[node.routerName][method]
 			if ok {
-				group.methodHandle(node.routerName, method, handle, ctx)
+				group.methodHandle(node.routerName, method, ctx)
+				return
+			}
+			//HEAD 没有注册时 使用 GET 处理 不返回 body
+			_, ok = group.handleFuncMap[node.routerName][http.MethodGet]
+			if ok && method == http.MethodHead {
+				ctx.W = &headResponseWriter{responseWriter: &ctx.writer}
+				group.methodHandle(node.routerName, http.MethodGet, ctx)
 				return
 			}
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			fmt.Fprintf(w, "%s %s not allowed \n", r.RequestURI, method)
+			ctx.W.Header().Set("Allow", group.allowMethods(node.routerName))
+			group.noMethod(ctx)
 			return
 		}
 	}
-	w.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(w, "%s  not found \n", r.RequestURI)
+	ctx.params = ctx.params[:0]
+	e.allNoRoute(ctx)
+}
+
+// notFound 默认的 404 也经过引擎级别的中间件 访问日志能记录到
+func (e *Engine) notFound(ctx *Context) {
+	if e.noRoute != nil {
+		e.noRoute(ctx)
+		return
+	}
+	ctx.W.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(ctx.W, "%s  not found \n", ctx.R.RequestURI)
+}
+
+// methodNotAllowed OPTIONS 没有注册时自动返回 204 其他方法返回 405 调用前已经设置了 Allow 头
+func (e *Engine) methodNotAllowed(ctx *Context) {
+	if ctx.R.Method == http.MethodOptions {
+		ctx.W.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if e.noMethod != nil {
+		e.noMethod(ctx)
+		return
+	}
+	ctx.W.WriteHeader(http.StatusMethodNotAllowed)
+	fmt.Fprintf(ctx.W, "%s %s not allowed \n", ctx.R.RequestURI, ctx.R.Method)
+}
+
+// NoRoute 没有匹配到路由时的处理 默认返回 404 文本 只经过引擎级别的中间件
+func (e *Engine) NoRoute(handlerFunc HandlerFunc) {
+	if e.isFrozen() {
+		panic("msgo: NoRoute called after the engine started serving")
+	}
+	e.noRoute = handlerFunc
+}
+
+// NoMethod 路由存在但请求方法不支持时的处理 调用前已经设置了 Allow 头
+// 和自动的 OPTIONS 响应一样经过引擎 域名 分组的中间件
+func (e *Engine) NoMethod(handlerFunc HandlerFunc) {
+	if e.isFrozen() {
+		panic("msgo: NoMethod called after the engine started serving")
+	}
+	e.noMethod = handlerFunc
+}
+
+// Host 按域名划分路由 admin.example.com 或者 *.example.com
+// 返回的路由有自己的分组和中间件 请求先按域名选择路由 没有匹配的域名时使用引擎默认的路由
+func (e *Engine) Host(host string) *router {
+	host = strings.ToLower(host)
+	for _, r := range e.hosts {
+		if r.host == host {
+			return r
+		}
+	}
+	r := &router{engine: e, host: host}
+	e.hosts = append(e.hosts, r)
+	return r
+}
+
+// matchHost 精确匹配优先 通配符中后缀最长的优先
+func (e *Engine) matchHost(host string) *router {
+	if len(e.hosts) == 0 {
+		return &e.router
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	var matched *router
+	for _, r := range e.hosts {
+		if r.host == host {
+			return r
+		}
+		if strings.HasPrefix(r.host, "*.") && strings.HasSuffix(host, r.host[1:]) {
+			if matched == nil || len(r.host) > len(matched.host) {
+				matched = r
+			}
+		}
+	}
+	if matched != nil {
+		return matched
+	}
+	return &e.router
+}
+
+func (e *Engine) routers() []*router {
+	return append([]*router{&e.router}, e.hosts...)
+}
+
+// freeze 第一次处理请求之前组合所有路由的调用链 之后 engine.Use 的中间件不再生效 也不能再注册路由
+func (e *Engine) freeze() {
+	e.freezeOnce.Do(func() {
+		for _, rt := range e.routers() {
+			for _, group := range rt.routerGroups {
+				for name, methods := range group.handleFuncMap {
+					for method := range methods {
+						group.compile(name, method)
+					}
+				}
+				group.noMethod = chain(e.methodNotAllowed, group.chainMiddlewares())
+			}
+		}
+		e.allNoRoute = chain(e.notFound, e.middles)
+		atomic.StoreInt32(&e.frozen, 1)
+	})
+}
+
+func (e *Engine) isFrozen() bool {
+	return atomic.LoadInt32(&e.frozen) == 1
+}
+
+// printRoutes 启动时打印路由表 方便排查中间件是否生效
+func (e *Engine) printRoutes() {
+	type route struct {
+		method      string
+		path        string
+		handler     string
+		middlewares []string
+	}
+	routes := make([]route, 0)
+	for _, r := range e.routers() {
+		for _, group := range r.routerGroups {
+			for name, methods := range group.handleFuncMap {
+				for method, h := range methods {
+					rt := route{method: method, path: r.host + group.prefix + name, handler: nameOfFunction(h)}
+					for _, m := range group.routeMiddlewares(name, method) {
+						rt.middlewares = append(rt.middlewares, nameOfFunction(m))
+					}
+					routes = append(routes, rt)
+				}
+			}
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].method < routes[j].method
+	})
+	for _, rt := range routes {
+		fmt.Fprintf(DefaultWriter, "[msgo] %-7s %-30s --> %s (%d middlewares: %s)\n",
+			rt.method, rt.path, rt.handler, len(rt.middlewares), strings.Join(rt.middlewares, ", "))
+	}
 }
 
 func (e *Engine) Run(addr string) {
-	//user  key:get value: func
-	//for _, group := range e.routerGroups {
-	//	for key, value := range group.handleFuncMap {
-	//		http.HandlerFunc("/"+group.Name+key, value)
-	//	}
-	//}
+	server := e.newServer(addr)
+	e.serve(server, func(ln net.Listener) error {
+		return server.Serve(ln)
+	})
+}
+
+func (e *Engine) RunTLS(addr, certFile, keyFile string) {
+	server := e.newServer(addr)
+	e.serve(server, func(ln net.Listener) error {
+		return server.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+func (e *Engine) newServer(addr string) *http.Server {
+	conf := config.Conf.Server
+	readHeaderTimeout := serverTimeout(e.ReadHeaderTimeout, conf["read_header_timeout"])
+	if readHeaderTimeout == 0 {
+		readHeaderTimeout = defaultReadHeaderTimeout
+	}
+	return &http.Server{
+		Addr:              addr,
+		Handler:           e.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       serverTimeout(e.ReadTimeout, conf["read_timeout"]),
+		WriteTimeout:      serverTimeout(e.WriteTimeout, conf["write_timeout"]),
+		IdleTimeout:       serverTimeout(e.IdleTimeout, conf["idle_timeout"]),
+	}
+}
+
+// serverTimeout 配置文件中可以写 "5s" "1m30s" 或者秒数
+func serverTimeout(d time.Duration, conf any) time.Duration {
+	if d != 0 {
+		return d
+	}
+	switch v := conf.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Printf("invalid server timeout %q: %v\n", v, err)
+			return 0
+		}
+		return d
+	case int64:
+		return time.Duration(v) * time.Second
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	}
+	return 0
+}
+
+// OnStart 端口监听成功后 开始处理请求之前执行
+func (e *Engine) OnStart(hooks ...func()) {
+	e.startHooks = append(e.startHooks, hooks...)
+}
+
+// OnShutdown 请求处理完之后执行 ctx 带有 ShutdownTimeout 的截止时间
+func (e *Engine) OnShutdown(hooks ...func(ctx context.Context)) {
+	e.shutdownHooks = append(e.shutdownHooks, hooks...)
+}
+
+func (e *Engine) createRegisterCli() {
 	if e.RegisterType == "nacos" {
 		r := &register.MsNacosRegister{}
 		err := r.CreateCli(e.RegisterOption)
@@ -300,17 +789,129 @@ func (e *Engine) Run(addr string) {
 		}
 		e.RegisterCli = r
 	}
-	http.Handle("/", e)
-	err := http.ListenAndServe(addr, nil)
+}
+
+// serve 监听端口并处理请求 收到 SIGINT/SIGTERM 后优雅关闭
+func (e *Engine) serve(server *http.Server, serve func(ln net.Listener) error) {
+	e.freeze()
+	e.printRoutes()
+	e.createRegisterCli()
+	ln, err := net.Listen("tcp", server.Addr)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := e.register(ln.Addr()); err != nil {
+		ln.Close()
+		log.Fatal(err)
+	}
+	for _, hook := range e.startHooks {
+		hook()
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve(ln)
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	case sig := <-quit:
+		log.Printf("receive signal %v, shutting down\n", sig)
+		e.shutdown(server)
+	}
 }
 
-func (e *Engine) RunTLS(addr, certFile, keyFile string) {
-	err := http.ListenAndServeTLS(addr, certFile, keyFile, e.Handler())
-	if err != nil {
-		log.Fatal(err)
+func (e *Engine) shutdown(server *http.Server) {
+	timeout := e.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	//先从注册中心摘掉 不再有新的流量进来
+	e.deregister()
+	//等待正在处理的请求完成
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+	for _, hook := range e.shutdownHooks {
+		hook(ctx)
+	}
+	if e.RegisterCli != nil {
+		if err := e.RegisterCli.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+	//异步写入的日志在 Close 时写完
+	if e.Logger != nil {
+		if err := e.Logger.Close(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// register Host 和 Port 没有配置时 使用实际监听的地址
+func (e *Engine) register(addr net.Addr) error {
+	if !e.AutoRegister {
+		return nil
+	}
+	if e.RegisterCli == nil {
+		return errors.New("AutoRegister requires RegisterType to be nacos or etcd")
+	}
+	option := &e.RegisterOption
+	if option.ServiceName == "" {
+		return errors.New("AutoRegister requires RegisterOption.ServiceName")
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if ok && option.Port == 0 {
+		option.Port = tcpAddr.Port
+	}
+	if ok && option.Host == "" {
+		if tcpAddr.IP.IsUnspecified() {
+			option.Host = localIP()
+		} else {
+			option.Host = tcpAddr.IP.String()
+		}
+	}
+	return e.RegisterService(option.ServiceName, option.Host, option.Port)
+}
+
+type registration struct {
+	serviceName string
+	host        string
+	port        int
+}
+
+// RegisterService 通过 RegisterCli 注册服务 关闭时和 AutoRegister 注册的一起摘除
+// RegisterCli 在 Run 中创建 可以在 OnStart 中调用
+func (e *Engine) RegisterService(serviceName string, host string, port int) error {
+	if e.RegisterCli == nil {
+		return errors.New("RegisterService requires RegisterType to be nacos or etcd")
+	}
+	if err := e.RegisterCli.RegisterService(serviceName, host, port); err != nil {
+		return err
+	}
+	e.registerMu.Lock()
+	e.registered = append(e.registered, registration{serviceName: serviceName, host: host, port: port})
+	e.registerMu.Unlock()
+	return nil
+}
+
+// deregister 后注册的先摘除
+func (e *Engine) deregister() {
+	e.registerMu.Lock()
+	registered := e.registered
+	e.registered = nil
+	e.registerMu.Unlock()
+	for i := len(registered) - 1; i >= 0; i-- {
+		reg := registered[i]
+		if err := e.RegisterCli.DeregisterService(reg.serviceName, reg.host, reg.port); err != nil {
+			log.Println(err)
+		}
 	}
 }
 
@@ -0,0 +1,349 @@
+package msgo
+
+import (
+	"fmt"
+	"github.com/mszlu521/msgo/register"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNestedGroup(t *testing.T) {
+	engine := New()
+	var order []string
+	mark := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(ctx *Context) {
+				order = append(order, name)
+				next(ctx)
+			}
+		}
+	}
+	api := engine.Group("api")
+	api.Use(mark("api"))
+	v1 := api.Group("v1")
+	v1.Use(mark("v1"))
+	v1.Get("/user/:id", func(ctx *Context) {
+		ctx.String(http.StatusOK, "v1 user %s", ctx.Param("id"))
+	}, mark("route"))
+	api.Get("/ping", func(ctx *Context) {
+		ctx.String(http.StatusOK, "pong")
+	})
+	user := engine.Group("user")
+	user.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "user info")
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/v1/user/1", http.StatusOK, "v1 user 1"},
+		{"/api/ping", http.StatusOK, "pong"},
+		{"/user/info", http.StatusOK, "user info"},
+		{"/api/user/info", http.StatusNotFound, ""},
+		{"/apiv1/user/1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	order = nil
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/user/1", nil))
+	if len(order) != 3 || order[0] != "api" || order[1] != "v1" || order[2] != "route" {
+		t.Errorf("middleware order = %v, want [api v1 route]", order)
+	}
+}
+
+func methodEngine() *Engine {
+	engine := New()
+	g := engine.Group("user")
+	g.Get("/info", func(ctx *Context) {
+		ctx.W.Header().Set("X-User", "1")
+		ctx.String(http.StatusOK, "user info")
+	})
+	g.Post("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "post")
+	})
+	return engine
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	engine := methodEngine()
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/user/info", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE code = %d, want 405", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Allow = %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "/user/info", nil))
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("X-User") != "1" {
+		t.Errorf("HEAD code = %d, body = %q, header = %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/user/info", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") == "" {
+		t.Errorf("OPTIONS code = %d, Allow = %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	//NoRoute NoMethod 需要在开始处理请求之前设置
+	engine = methodEngine()
+	engine.NoRoute(func(ctx *Context) {
+		ctx.JSON(http.StatusNotFound, map[string]any{"code": 404})
+	})
+	engine.NoMethod(func(ctx *Context) {
+		ctx.JSON(http.StatusMethodNotAllowed, map[string]any{"code": 405})
+	})
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/none", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"code":404}` {
+		t.Errorf("NoRoute code = %d, body = %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/user/info", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != `{"code":405}` || w.Header().Get("Allow") == "" {
+		t.Errorf("NoMethod code = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMethodNotAllowedMiddleware(t *testing.T) {
+	engine := New()
+	var status []int
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = append(status, ctx.writer.Status())
+		}
+	})
+	g := engine.Group("user")
+	//分组上的跨域中间件 自动的 OPTIONS 响应也要经过
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.W.Header().Set("Access-Control-Allow-Origin", "*")
+			next(ctx)
+		}
+	})
+	g.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "user info")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/user/info", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("OPTIONS code = %d, header = %v", w.Code, w.Header())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/user/info", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("DELETE code = %d, header = %v", w.Code, w.Header())
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/none", nil))
+	if len(status) != 3 || status[0] != http.StatusNoContent || status[1] != http.StatusMethodNotAllowed || status[2] != http.StatusNotFound {
+		t.Errorf("middleware saw status %v, want [204 405 404]", status)
+	}
+}
+
+func TestRegisterAfterServe(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	g.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "user info")
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/info", nil))
+
+	if err := g.Handle(http.MethodGet, "/late", func(ctx *Context) {}); err == nil {
+		t.Error("Handle after serving should return an error")
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/late", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("late route code = %d, want 404", w.Code)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Get after serving should panic")
+		}
+	}()
+	g.Get("/late", func(ctx *Context) {})
+}
+
+func TestEngineUseAfterGroup(t *testing.T) {
+	engine := New()
+	g := engine.Group("user")
+	g.Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "user info")
+	})
+	//分组创建之后添加的引擎中间件也要生效
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.W.Header().Set("X-Engine", "1")
+			next(ctx)
+		}
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/info", nil))
+	if w.Header().Get("X-Engine") != "1" {
+		t.Error("engine middleware registered after Group was not applied")
+	}
+}
+
+func TestAbort(t *testing.T) {
+	engine := New()
+	var status int
+	engine.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = ctx.Writer().Status()
+		}
+	})
+	g := engine.Group("user")
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if ctx.GetHeader("Authorization") == "" {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, map[string]any{"code": 401})
+			}
+			//忘记 return 也不会执行后面的处理函数
+			next(ctx)
+		}
+	})
+	called := false
+	g.Get("/info", func(ctx *Context) {
+		called = true
+		ctx.W.WriteHeader(http.StatusAccepted)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/info", nil))
+	if called || w.Code != http.StatusUnauthorized || status != http.StatusUnauthorized {
+		t.Errorf("called = %v, code = %d, status = %d", called, w.Code, status)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/user/info", nil)
+	r.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if !called || status != http.StatusAccepted {
+		t.Errorf("called = %v, status = %d, want 202 written directly to ctx.W", called, status)
+	}
+}
+
+func TestHost(t *testing.T) {
+	engine := New()
+	admin := engine.Host("admin.example.com")
+	admin.Use(func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.W.Header().Set("X-Host", "admin")
+			next(ctx)
+		}
+	})
+	admin.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "admin user")
+	})
+	engine.Host("*.example.com").Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "tenant user")
+	})
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.String(http.StatusOK, "default user")
+	})
+
+	tests := []struct {
+		host string
+		body string
+	}{
+		{"admin.example.com", "admin user"},
+		{"Admin.Example.com:8080", "admin user"},
+		{"api.example.com", "tenant user"},
+		{"example.com", "default user"},
+		{"localhost", "default user"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/user/info", nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.host, w.Body.String(), tt.body)
+		}
+		if (tt.body == "admin user") != (w.Header().Get("X-Host") == "admin") {
+			t.Errorf("%s: host middleware applied = %q", tt.host, w.Header().Get("X-Host"))
+		}
+	}
+}
+
+// fakeRegister 记录注册和摘除的服务
+type fakeRegister struct {
+	services []string
+	closed   bool
+}
+
+func (r *fakeRegister) CreateCli(option register.Option) error {
+	return nil
+}
+
+func (r *fakeRegister) RegisterService(serviceName string, host string, port int) error {
+	r.services = append(r.services, fmt.Sprintf("%s %s:%d", serviceName, host, port))
+	return nil
+}
+
+func (r *fakeRegister) DeregisterService(serviceName string, host string, port int) error {
+	name := fmt.Sprintf("%s %s:%d", serviceName, host, port)
+	for i, s := range r.services {
+		if s == name {
+			r.services = append(r.services[:i], r.services[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not registered", name)
+}
+
+func (r *fakeRegister) GetValue(serviceName string) (string, error) {
+	return "", nil
+}
+
+func (r *fakeRegister) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestShutdownDeregister(t *testing.T) {
+	for _, auto := range []bool{true, false} {
+		cli := &fakeRegister{}
+		engine := New()
+		engine.RegisterCli = cli
+		engine.AutoRegister = auto
+		engine.RegisterOption = register.Option{ServiceName: "goods"}
+		if err := engine.register(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9002}); err != nil {
+			t.Fatal(err)
+		}
+		//手动注册的服务也要在关闭时摘除
+		if err := engine.RegisterService("goods-admin", "10.0.0.1", 9003); err != nil {
+			t.Fatal(err)
+		}
+		want := 1
+		if auto {
+			want = 2
+		}
+		if len(cli.services) != want {
+			t.Fatalf("auto = %v: registered %v", auto, cli.services)
+		}
+		engine.shutdown(&http.Server{})
+		if len(cli.services) != 0 || !cli.closed {
+			t.Errorf("auto = %v: after shutdown services = %v, closed = %v", auto, cli.services, cli.closed)
+		}
+	}
+}
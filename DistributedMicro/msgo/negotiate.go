@@ -0,0 +1,123 @@
+package msgo
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON     = "application/json"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEHTML     = "text/html"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/x-yaml"
+	MIMEPROTOBUF = "application/x-protobuf"
+	MIMEMSGPACK  = "application/msgpack"
+)
+
+var negotiateOffers = []string{MIMEJSON, MIMEXML, MIMEHTML, MIMEPlain, MIMEYAML, MIMEMSGPACK}
+
+// Negotiate 根据 Accept 选择 JSON XML HTML 纯文本 YAML 或 MsgPack 没有可用的格式时返回 406
+// data 是 proto.Message 时还可以返回 protobuf
+func (c *Context) Negotiate(status int, data any) error {
+	offers := negotiateOffers
+	if _, ok := data.(proto.Message); ok {
+		offers = append([]string{MIMEJSON, MIMEPROTOBUF}, negotiateOffers[1:]...)
+	}
+	switch c.NegotiateFormat(offers...) {
+	case MIMEJSON:
+		return c.JSON(status, data)
+	case MIMEXML:
+		return c.XML(status, data)
+	case MIMEYAML:
+		return c.YAML(status, data)
+	case MIMEPROTOBUF:
+		return c.ProtoBuf(status, data)
+	case MIMEMSGPACK:
+		return c.MsgPack(status, data)
+	case MIMEHTML:
+		//只有 template.HTML 原样输出 字符串等其他数据都转义 避免用户输入的内容造成 XSS
+		if html, ok := data.(template.HTML); ok {
+			return c.HTML(status, string(html))
+		}
+		return c.HTML(status, template.HTMLEscapeString(fmt.Sprint(data)))
+	case MIMEPlain:
+		return c.String(status, "%v", data)
+	default:
+		return c.String(http.StatusNotAcceptable, "%s not acceptable", c.GetHeader("Accept"))
+	}
+}
+
+// NegotiateFormat 按 Accept 中的 q 值从 offered 中选出一个 没有 Accept 时返回第一个
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accepts := parseAccept(c.GetHeader("Accept"))
+	if len(accepts) == 0 {
+		return offered[0]
+	}
+	for _, accept := range accepts {
+		for _, offer := range offered {
+			if matchMIME(accept, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// parseAccept text/html,application/json;q=0.9,*/*;q=0.8 按 q 值从高到低排序 去掉 q=0
+func parseAccept(header string) []string {
+	type accept struct {
+		mime string
+		q    float64
+	}
+	accepts := make([]accept, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(params[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			accepts = append(accepts, accept{mime: mime, q: q})
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].q > accepts[j].q
+	})
+	mimes := make([]string, len(accepts))
+	for i, a := range accepts {
+		mimes[i] = a.mime
+	}
+	return mimes
+}
+
+func matchMIME(accept string, offer string) bool {
+	if accept == "*/*" || accept == offer {
+		return true
+	}
+	if accept == MIMEXML2 && offer == MIMEXML {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(offer, accept[:len(accept)-1])
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package msgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	engine := New()
+	engine.Group("user").Get("/info", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, map[string]string{"name": "msgo"})
+	})
+	tests := []struct {
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", http.StatusOK, MIMEJSON},
+		{"application/xml", http.StatusOK, MIMEXML},
+		{"text/xml;q=0.5, text/plain", http.StatusOK, MIMEPlain},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK, MIMEHTML},
+		{"image/png", http.StatusNotAcceptable, MIMEPlain},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/user/info", nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.code || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%q: code = %d, content type = %s", tt.accept, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestNegotiateHTMLEscape(t *testing.T) {
+	engine := New()
+	g := engine.Group("html")
+	g.Get("/string", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, "<script>alert(1)</script>")
+	})
+	g.Get("/raw", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, template.HTML("<b>msgo</b>"))
+	})
+	tests := map[string]string{
+		"/html/string": "&lt;script&gt;alert(1)&lt;/script&gt;",
+		"/html/raw":    "<b>msgo</b>",
+	}
+	for path, want := range tests {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Body.String() != want {
+			t.Errorf("%s: body = %q, want %q", path, w.Body.String(), want)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	type User struct {
+		Id   int64  `uri:"id" json:"id"`
+		Name string `form:"name" json:"name" xml:"name"`
+		Age  int    `form:"age" json:"age" xml:"age"`
+	}
+	engine := New()
+	var user User
+	engine.Group("user").Any("/:id", func(ctx *Context) {
+		user = User{}
+		if err := ctx.Bind(&user); err != nil {
+			t.Error(err)
+		}
+	})
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+	}{
+		{http.MethodGet, "/user/1?name=msgo&age=18", "", ""},
+		{http.MethodPost, "/user/1", "application/json", `{"name":"msgo","age":18}`},
+		{http.MethodPost, "/user/1", "application/xml", `<User><name>msgo</name><age>18</age></User>`},
+		{http.MethodPost, "/user/1", "application/x-www-form-urlencoded", "name=msgo&age=18"},
+		{http.MethodPut, "/user/1?age=18", "multipart/form-data; boundary=xx", "--xx\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nmsgo\r\n--xx--\r\n"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		engine.ServeHTTP(httptest.NewRecorder(), r)
+		if user.Id != 1 || user.Name != "msgo" || (user.Age != 18 && tt.method != http.MethodPut) {
+			t.Errorf("%s %s: user = %+v", tt.method, tt.contentType, user)
+		}
+	}
+}
+
+// TestBindNonStruct 有路由参数时也可以绑定到 map 和切片
+func TestBindNonStruct(t *testing.T) {
+	engine := New()
+	var m map[string]any
+	var list []map[string]any
+	engine.Group("u").Post("/:id", func(ctx *Context) {
+		if ctx.GetHeader("X-Kind") == "slice" {
+			list = nil
+			_ = ctx.Bind(&list)
+			return
+		}
+		m = nil
+		_ = ctx.Bind(&m)
+	})
+	tests := []struct {
+		kind string
+		body string
+	}{
+		{"map", `{"name":"msgo"}`},
+		{"slice", `[{"name":"msgo"}]`},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/u/1", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Kind", tt.kind)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: code = %d body = %s", tt.kind, w.Code, w.Body.String())
+		}
+	}
+	if m["name"] != "msgo" || len(list) != 1 || list[0]["name"] != "msgo" {
+		t.Errorf("map = %v, slice = %v", m, list)
+	}
+}
+
+func TestBindValidationError(t *testing.T) {
+	type User struct {
+		Name string `json:"name" validate:"required"`
+		Age  int    `json:"age" validate:"gte=0,lte=150"`
+	}
+	engine := New()
+	engine.Group("user").Post("/add", func(ctx *Context) {
+		_ = ctx.Bind(&User{})
+	})
+	r := httptest.NewRequest(http.MethodPost, "/user/add", bytes.NewBufferString(`{"age":200}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("code = %d", w.Code)
+	}
+	var body struct {
+		Code   int
+		Errors []struct {
+			Field, Rule, Param, Message string
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != 400 || len(body.Errors) != 2 || body.Errors[0].Field != "name" ||
+		body.Errors[1].Rule != "lte" || body.Errors[1].Param != "150" || body.Errors[0].Message != "name is a required field" {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
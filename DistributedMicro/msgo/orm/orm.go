@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -27,6 +28,7 @@ type MsSession struct {
 	updateParam strings.Builder
 	whereParam  strings.Builder
 	whereValues []any
+	ctx         context.Context
 }
 
 func Open(driverName string, source string) *MsDb {
@@ -63,6 +65,10 @@ func (db *MsDb) SetMaxIdleConns(n int) {
 	db.db.SetMaxIdleConns(n)
 }
 
+func (db *MsDb) SetLogger(logger *msLog.Logger) {
+	db.logger = logger
+}
+
 func (db *MsDb) New(data any) *MsSession {
 	m := &MsSession{
 		db: db,
@@ -77,6 +83,20 @@ func (db *MsDb) New(data any) *MsSession {
 	}
 	return m
 }
+
+// WithContext sql 日志带上 ctx 中的 request_id trace_id 等字段
+func (s *MsSession) WithContext(ctx context.Context) *MsSession {
+	s.ctx = ctx
+	return s
+}
+
+func (s *MsSession) logger() *msLog.Logger {
+	if s.ctx == nil {
+		return s.db.logger
+	}
+	return s.db.logger.WithContext(s.ctx)
+}
+
 func (s *MsSession) Table(name string) *MsSession {
 	s.tableName = name
 	return s
@@ -86,7 +106,7 @@ func (s *MsSession) Insert(data any) (int64, int64, error) {
 	//insert into table (xxx,xxx) values(?,?)
 	s.fieldNames(data)
 	query := fmt.Sprintf("insert into %s (%s) values (%s)", s.tableName, strings.Join(s.fieldName, ","), strings.Join(s.placeHolder, ","))
-	s.db.logger.Info(query)
+	s.logger().Info(query)
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
@@ -167,7 +187,7 @@ func (s *MsSession) InsertBatch(data []any) (int64, int64, error) {
 		}
 	}
 	s.batchValues(data)
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
@@ -226,7 +246,7 @@ func (s *MsSession) Update(data ...any) (int64, int64, error) {
 		var sb strings.Builder
 		sb.WriteString(query)
 		sb.WriteString(s.whereParam.String())
-		s.db.logger.Info(sb.String())
+		s.logger().Info(sb.String())
 		var stmt *sql.Stmt
 		var err error
 		if s.beginTx {
@@ -304,7 +324,7 @@ func (s *MsSession) Update(data ...any) (int64, int64, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 	var stmt *sql.Stmt
 	var err error
 	if s.beginTx {
@@ -337,7 +357,7 @@ func (s *MsSession) Delete() (int64, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 
 	var stmt *sql.Stmt
 	var err error
@@ -369,7 +389,7 @@ func (s *MsSession) Select(data any, fields ...string) ([]any, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
@@ -450,7 +470,7 @@ func (s *MsSession) SelectOne(data any, fields ...string) error {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
@@ -520,7 +540,7 @@ func (s *MsSession) Aggregate(funcName string, field string) (int64, error) {
 	var sb strings.Builder
 	sb.WriteString(query)
 	sb.WriteString(s.whereParam.String())
-	s.db.logger.Info(sb.String())
+	s.logger().Info(sb.String())
 
 	stmt, err := s.db.db.Prepare(sb.String())
 	if err != nil {
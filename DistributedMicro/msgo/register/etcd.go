@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"log"
+	"sync"
 	"time"
 )
 
@@ -37,8 +39,22 @@ func GetEtcdValue(cli *clientv3.Client, serviceName string) (string, error) {
 	return string(kvs[0].Value), err
 }
 
+// defaultEtcdTTL Option.TTL 没有设置时的租约时间
+const defaultEtcdTTL = 10 * time.Second
+
+// etcdClient MsEtcdRegister 用到的 etcd 接口 *clientv3.Client 实现了它 测试时替换
+type etcdClient interface {
+	clientv3.KV
+	clientv3.Lease
+}
+
 type MsEtcdRegister struct {
-	cli *clientv3.Client
+	cli    etcdClient
+	ttl    time.Duration
+	leases map[string]clientv3.LeaseID
+	mu     sync.Mutex
+	//续期中断后重新注册失败时的重试间隔
+	retryInterval time.Duration
 }
 
 func (r *MsEtcdRegister) CreateCli(option Option) error {
@@ -46,14 +62,126 @@ func (r *MsEtcdRegister) CreateCli(option Option) error {
 		Endpoints:   option.Endpoints,   //节点
 		DialTimeout: option.DialTimeout, //超过5秒钟连不上超时
 	})
+	if err != nil {
+		return err
+	}
+	r.init(cli, option.TTL)
+	return nil
+}
+
+func (r *MsEtcdRegister) init(cli etcdClient, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultEtcdTTL
+	}
 	r.cli = cli
-	return err
+	r.ttl = ttl
+	r.leases = make(map[string]clientv3.LeaseID)
+	r.retryInterval = time.Second
 }
 
 func (r *MsEtcdRegister) RegisterService(serviceName string, host string, port int) error {
+	value := fmt.Sprintf("%s:%d", host, port)
+	//带租约注册 通过 KeepAlive 续期 进程挂掉后租约过期 服务自动摘除
+	leaseID, ch, err := r.grant(serviceName, value)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.leases[serviceName] = leaseID
+	r.mu.Unlock()
+	go r.keepAlive(serviceName, value, leaseID, ch)
+	return nil
+}
+
+// grant 申请租约 写入 key 并开始续期
+func (r *MsEtcdRegister) grant(serviceName string, value string) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	_, err := r.cli.Put(ctx, serviceName, fmt.Sprintf("%s:%d", host, port))
+	ttl := int64(r.ttl / time.Second)
+	if ttl < 1 {
+		ttl = 1
+	}
+	lease, err := r.cli.Grant(ctx, ttl)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, err = r.cli.Put(ctx, serviceName, value, clientv3.WithLease(lease.ID))
+	if err == nil {
+		var ch <-chan *clientv3.LeaseKeepAliveResponse
+		ch, err = r.cli.KeepAlive(context.Background(), lease.ID)
+		if err == nil {
+			return lease.ID, ch, nil
+		}
+	}
+	_, _ = r.cli.Revoke(ctx, lease.ID)
+	return 0, nil, err
+}
+
+// keepAlive 需要读取续期的响应 否则 channel 满了之后会打印告警
+// channel 关闭说明续期中断(如 etcd 长时间不可用 租约已经过期) 服务还没有摘除时重新申请租约注册
+func (r *MsEtcdRegister) keepAlive(serviceName string, value string, leaseID clientv3.LeaseID, ch <-chan *clientv3.LeaseKeepAliveResponse) {
+	for {
+		for range ch {
+		}
+		for {
+			if !r.holds(serviceName, leaseID) {
+				return
+			}
+			newID, newCh, err := r.grant(serviceName, value)
+			if err != nil {
+				log.Printf("etcd: re-register %s: %v\n", serviceName, err)
+				time.Sleep(r.retryInterval)
+				continue
+			}
+			r.mu.Lock()
+			if r.leases[serviceName] != leaseID {
+				//重新注册期间服务被摘除了
+				r.mu.Unlock()
+				r.revoke(newID)
+				return
+			}
+			r.leases[serviceName] = newID
+			r.mu.Unlock()
+			leaseID, ch = newID, newCh
+			break
+		}
+	}
+}
+
+func (r *MsEtcdRegister) holds(serviceName string, leaseID clientv3.LeaseID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := r.leases[serviceName]
+	return ok && id == leaseID
+}
+
+func (r *MsEtcdRegister) revoke(leaseID clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _ = r.cli.Revoke(ctx, leaseID)
+}
+
+func (r *MsEtcdRegister) DeregisterService(serviceName string, host string, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r.mu.Lock()
+	leaseID, ok := r.leases[serviceName]
+	delete(r.leases, serviceName)
+	r.mu.Unlock()
+	if ok {
+		//撤销租约会同时删除租约上的 key 并停止续期 其他实例写入的 key 不在这个租约上
+		_, err := r.cli.Revoke(ctx, leaseID)
+		return err
+	}
+	//只删除自己注册的地址 避免把同名的其他实例删掉
+	v, err := r.cli.Get(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+	if len(v.Kvs) == 0 || string(v.Kvs[0].Value) != fmt.Sprintf("%s:%d", host, port) {
+		return nil
+	}
+	_, err = r.cli.Delete(ctx, serviceName)
 	return err
 }
 
@@ -71,6 +199,10 @@ func (r *MsEtcdRegister) GetValue(serviceName string) (string, error) {
 	return string(kvs[0].Value), err
 }
 
+// Close 关闭后不再重新注册 租约等待过期
 func (r *MsEtcdRegister) Close() error {
+	r.mu.Lock()
+	r.leases = make(map[string]clientv3.LeaseID)
+	r.mu.Unlock()
 	return r.cli.Close()
 }
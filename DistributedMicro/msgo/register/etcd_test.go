@@ -0,0 +1,206 @@
+package register
+
+import (
+	"context"
+	"errors"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEtcd 内存中的 etcd 只实现 MsEtcdRegister 用到的方法
+// Put 带有选项时认为是写到最近申请的租约上
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Lease
+	mu        sync.Mutex
+	kvs       map[string]string
+	keyLease  map[string]clientv3.LeaseID
+	keepAlive map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	lastLease clientv3.LeaseID
+	grantErr  error
+	closed    bool
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{
+		kvs:       make(map[string]string),
+		keyLease:  make(map[string]clientv3.LeaseID),
+		keepAlive: make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+	}
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kvs[key] = val
+	delete(f.keyLease, key)
+	if len(opts) > 0 {
+		f.keyLease[key] = f.lastLease
+	}
+	return &clientv3.PutResponse{}, nil
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &clientv3.GetResponse{}
+	if v, ok := f.kvs[key]; ok {
+		resp.Kvs = []*mvccpb.KeyValue{{Key: []byte(key), Value: []byte(v)}}
+	}
+	return resp, nil
+}
+
+func (f *fakeEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.kvs, key)
+	delete(f.keyLease, key)
+	return &clientv3.DeleteResponse{}, nil
+}
+
+func (f *fakeEtcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.grantErr != nil {
+		return nil, f.grantErr
+	}
+	f.lastLease++
+	return &clientv3.LeaseGrantResponse{ID: f.lastLease, TTL: ttl}, nil
+}
+
+func (f *fakeEtcd) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	f.expire(id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (f *fakeEtcd) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan *clientv3.LeaseKeepAliveResponse, 1)
+	ch <- &clientv3.LeaseKeepAliveResponse{ID: id}
+	f.keepAlive[id] = ch
+	return ch, nil
+}
+
+func (f *fakeEtcd) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// expire 租约过期 删除租约上的 key 并关闭续期的 channel
+func (f *fakeEtcd) expire(id clientv3.LeaseID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, lease := range f.keyLease {
+		if lease == id {
+			delete(f.kvs, key)
+			delete(f.keyLease, key)
+		}
+	}
+	if ch, ok := f.keepAlive[id]; ok {
+		close(ch)
+		delete(f.keepAlive, id)
+	}
+}
+
+func (f *fakeEtcd) value(key string) (string, clientv3.LeaseID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.kvs[key], f.keyLease[key]
+}
+
+func (f *fakeEtcd) setGrantErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.grantErr = err
+}
+
+func newTestRegister(cli etcdClient, ttl time.Duration) *MsEtcdRegister {
+	r := &MsEtcdRegister{}
+	r.init(cli, ttl)
+	r.retryInterval = 10 * time.Millisecond
+	return r
+}
+
+// waitFor 后台协程重新注册 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEtcdRegisterLease(t *testing.T) {
+	cli := newFakeEtcd()
+	r := newTestRegister(cli, 5*time.Second)
+	if err := r.RegisterService("goods", "10.0.0.1", 9002); err != nil {
+		t.Fatal(err)
+	}
+	value, lease := cli.value("goods")
+	if value != "10.0.0.1:9002" || lease == 0 {
+		t.Fatalf("registered value = %q, lease = %d", value, lease)
+	}
+
+	//续期中断 租约过期后重新申请租约注册
+	cli.setGrantErr(errors.New("etcdserver: no leader"))
+	cli.expire(lease)
+	time.Sleep(30 * time.Millisecond)
+	if value, _ := cli.value("goods"); value != "" {
+		t.Fatalf("value = %q while etcd is unavailable", value)
+	}
+	cli.setGrantErr(nil)
+	waitFor(t, func() bool {
+		value, newLease := cli.value("goods")
+		return value == "10.0.0.1:9002" && newLease != 0 && newLease != lease
+	})
+
+	if err := r.DeregisterService("goods", "10.0.0.1", 9002); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cli.value("goods"); value != "" {
+		t.Errorf("value = %q after deregister", value)
+	}
+	//摘除之后不会再重新注册
+	time.Sleep(30 * time.Millisecond)
+	if value, _ := cli.value("goods"); value != "" {
+		t.Errorf("value = %q re-registered after deregister", value)
+	}
+}
+
+func TestEtcdDeregister(t *testing.T) {
+	cli := newFakeEtcd()
+	r := newTestRegister(cli, 0)
+	if err := r.RegisterService("goods", "10.0.0.1", 9002); err != nil {
+		t.Fatal(err)
+	}
+	//没有设置 TTL 时也带租约注册 进程挂掉后自动过期
+	if _, lease := cli.value("goods"); lease == 0 {
+		t.Error("TTL 0 registered without a lease")
+	}
+	//其他实例覆盖之后 不删除别人的地址
+	cli.Put(context.Background(), "goods", "10.0.0.2:9002")
+	if err := r.DeregisterService("goods", "10.0.0.1", 9002); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cli.value("goods"); value != "10.0.0.2:9002" {
+		t.Errorf("value = %q, other instance removed", value)
+	}
+	if err := r.DeregisterService("goods", "10.0.0.2", 9002); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := cli.value("goods"); value != "" {
+		t.Errorf("value = %q after deregister", value)
+	}
+	if err := r.Close(); err != nil || !cli.closed {
+		t.Errorf("Close err = %v, closed = %v", err, cli.closed)
+	}
+}
@@ -108,6 +108,7 @@ func (r *MsNacosRegister) CreateCli(option Option) error {
 	return nil
 }
 
+// RegisterService 注册临时实例 nacos 客户端会自动发送心跳 停止心跳后实例被摘除
 func (r *MsNacosRegister) RegisterService(serviceName string, host string, port int) error {
 	_, err := r.cli.RegisterInstance(vo.RegisterInstanceParam{
 		Ip:          host,
@@ -123,6 +124,17 @@ func (r *MsNacosRegister) RegisterService(serviceName string, host string, port
 	})
 	return err
 }
+
+func (r *MsNacosRegister) DeregisterService(serviceName string, host string, port int) error {
+	_, err := r.cli.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          host,
+		Port:        uint64(port),
+		ServiceName: serviceName,
+		Ephemeral:   true,
+	})
+	return err
+}
+
 func (r *MsNacosRegister) GetValue(serviceName string) (string, error) {
 	instance, err := r.cli.SelectOneHealthyInstance(vo.SelectOneHealthInstanceParam{
 		ServiceName: serviceName,
@@ -11,6 +11,7 @@ type Option struct {
 	ServiceName       string
 	Host              string
 	Port              int
+	TTL               time.Duration //etcd 租约时间 默认 10s 注册的服务随租约续期 进程退出后自动过期
 	NacosServerConfig []constant.ServerConfig
 	NacosClientConfig *constant.ClientConfig
 }
@@ -18,6 +19,7 @@ type Option struct {
 type MsRegister interface {
 	CreateCli(option Option) error
 	RegisterService(serviceName string, host string, port int) error
+	DeregisterService(serviceName string, host string, port int) error
 	GetValue(serviceName string) (string, error)
 	Close() error
 }
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"bytes"
 	"github.com/mszlu521/msgo/internal/bytesconv"
 	"html/template"
 	"net/http"
@@ -12,17 +13,21 @@ type HTML struct {
 	Template   *template.Template
 	IsTemplate bool
 }
-type HTMLRender struct {
-	Template *template.Template
-}
 
+// Render 模板先执行到缓冲区 出错时不会写出状态码和半个页面 调用方还可以返回 500
 func (h *HTML) Render(w http.ResponseWriter, code int) error {
-	h.WriteContentType(w)
-	w.WriteHeader(code)
 	if h.IsTemplate {
-		err := h.Template.ExecuteTemplate(w, h.Name, h.Data)
+		var buf bytes.Buffer
+		if err := h.Template.ExecuteTemplate(&buf, h.Name, h.Data); err != nil {
+			return err
+		}
+		h.WriteContentType(w)
+		w.WriteHeader(code)
+		_, err := buf.WriteTo(w)
 		return err
 	}
+	h.WriteContentType(w)
+	w.WriteHeader(code)
 	_, err := w.Write(bytesconv.StringToBytes(h.Data.(string)))
 	return err
 }
@@ -1,8 +1,11 @@
 package render
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"regexp"
 )
 
 type JSON struct {
@@ -23,3 +26,88 @@ func (j *JSON) Render(w http.ResponseWriter, code int) error {
 func (j *JSON) WriteContentType(w http.ResponseWriter) {
 	writeContentType(w, "application/json; charset=utf-8")
 }
+
+// PureJSON 不转义 < > & 等 HTML 字符
+type PureJSON struct {
+	Data any
+}
+
+func (j *PureJSON) Render(w http.ResponseWriter, code int) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(j.Data); err != nil {
+		return err
+	}
+	j.WriteContentType(w)
+	w.WriteHeader(code)
+	//Encode 会在末尾加上换行
+	_, err := w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return err
+}
+
+func (j *PureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json; charset=utf-8")
+}
+
+// IndentedJSON 格式化输出 便于调试
+type IndentedJSON struct {
+	Data any
+}
+
+func (j *IndentedJSON) Render(w http.ResponseWriter, code int) error {
+	jsonData, err := json.MarshalIndent(j.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	j.WriteContentType(w)
+	w.WriteHeader(code)
+	_, err = w.Write(jsonData)
+	return err
+}
+
+func (j *IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json; charset=utf-8")
+}
+
+// callback 只允许 js 标识符 可以用 . 连接 如 jQuery123.cb
+var callbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+var ErrInvalidCallback = errors.New("invalid jsonp callback")
+
+// ValidCallback callback 是否可以直接输出到脚本中
+func ValidCallback(callback string) bool {
+	return len(callback) <= 128 && callbackPattern.MatchString(callback)
+}
+
+// JSONP 输出 /**/callback(data); callback 不合法时返回 ErrInvalidCallback 不写任何内容
+type JSONP struct {
+	Callback string
+	Data     any
+}
+
+func (j *JSONP) Render(w http.ResponseWriter, code int) error {
+	if !ValidCallback(j.Callback) {
+		return ErrInvalidCallback
+	}
+	jsonData, err := json.Marshal(j.Data)
+	if err != nil {
+		return err
+	}
+	j.WriteContentType(w)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	//开头的注释防止 Rosetta Flash 之类利用响应开头字节的攻击
+	var buf bytes.Buffer
+	buf.WriteString("/**/")
+	buf.WriteString(j.Callback)
+	buf.WriteByte('(')
+	buf.Write(jsonData)
+	buf.WriteString(");")
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (j *JSONP) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/javascript; charset=utf-8")
+}
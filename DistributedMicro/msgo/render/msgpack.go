@@ -0,0 +1,26 @@
+package render
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+)
+
+// MsgPack 结构体字段使用 msgpack 标签 没有时使用字段名
+type MsgPack struct {
+	Data any
+}
+
+func (m *MsgPack) Render(w http.ResponseWriter, code int) error {
+	data, err := msgpack.Marshal(m.Data)
+	if err != nil {
+		return err
+	}
+	m.WriteContentType(w)
+	w.WriteHeader(code)
+	_, err = w.Write(data)
+	return err
+}
+
+func (m *MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/msgpack")
+}
@@ -0,0 +1,31 @@
+package render
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+)
+
+// ProtoBuf Data 必须是 proto.Message 如 rpc 包中 protoc 生成的结构体
+type ProtoBuf struct {
+	Data any
+}
+
+func (p *ProtoBuf) Render(w http.ResponseWriter, code int) error {
+	message, ok := p.Data.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", p.Data)
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	p.WriteContentType(w)
+	w.WriteHeader(code)
+	_, err = w.Write(data)
+	return err
+}
+
+func (p *ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-protobuf")
+}
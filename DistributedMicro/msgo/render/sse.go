@@ -0,0 +1,77 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SSEvent Server-Sent Events 的一条事件
+// Data 是字符串或 []byte 时原样发送 其他类型编码为 json 多行数据每行一个 data 字段
+type SSEvent struct {
+	Id    string
+	Event string
+	//客户端断开后重连的间隔 毫秒 0 表示不发送
+	Retry uint
+	Data  any
+}
+
+func (s *SSEvent) Render(w http.ResponseWriter, code int) error {
+	s.WriteContentType(w)
+	w.WriteHeader(code)
+	return s.Encode(w)
+}
+
+func (s *SSEvent) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	//nginx 默认会缓存响应 事件无法及时到达
+	header.Set("X-Accel-Buffering", "no")
+}
+
+// Encode 按 id event retry data 的顺序写出 以空行结束
+func (s *SSEvent) Encode(w io.Writer) error {
+	var b strings.Builder
+	if s.Id != "" {
+		b.WriteString("id:" + escapeField(s.Id) + "\n")
+	}
+	if s.Event != "" {
+		b.WriteString("event:" + escapeField(s.Event) + "\n")
+	}
+	if s.Retry > 0 {
+		fmt.Fprintf(&b, "retry:%d\n", s.Retry)
+	}
+	data, err := s.data()
+	if err != nil {
+		return err
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data:" + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func (s *SSEvent) data() (string, error) {
+	switch data := s.Data.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return data, nil
+	case []byte:
+		return string(data), nil
+	}
+	jsonData, err := json.Marshal(s.Data)
+	return string(jsonData), err
+}
+
+// escapeField id 和 event 中的换行会被当成新的字段
+func escapeField(s string) string {
+	return strings.NewReplacer("\n", "", "\r", "").Replace(s)
+}
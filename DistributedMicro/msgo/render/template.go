@@ -0,0 +1,273 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TemplateConfig 模板的加载方式
+// 没有 Layouts 时所有文件解析到同一个集合中 和 template.ParseGlob 一样按文件名执行
+// 有 Layouts 时每个页面和布局 公共片段单独解析 页面用 {{define "content"}} 覆盖布局中的 {{block "content" .}}
+type TemplateConfig struct {
+	//模板所在的文件系统 embed.FS 可以直接使用 为空时读取本地文件
+	FS fs.FS
+	//页面模板 可以有多个 如 tpl/*.html tpl/user/*.html
+	Patterns []string
+	//布局和公共片段 每个页面都会和它们一起解析
+	Layouts []string
+	//执行的布局模板名 为空时直接执行页面
+	Layout  string
+	FuncMap template.FuncMap
+	//开发模式 渲染时检查文件是否有变化 有变化时重新解析
+	Dev bool
+	//开发模式下两次检查文件的最小间隔 默认 1s
+	CheckInterval time.Duration
+}
+
+type HTMLRender struct {
+	//没有 Layouts 时是所有页面的模板集合 有 Layouts 时是布局和公共片段的集合
+	//Dev 模式重新解析后不会更新 渲染时以 Lookup 返回的为准
+	Template *template.Template
+	loader   *templateLoader
+}
+
+// NewHTMLRender 按配置解析模板 生产模式只解析这一次
+func NewHTMLRender(conf TemplateConfig) (HTMLRender, error) {
+	if conf.Dev && conf.CheckInterval <= 0 {
+		conf.CheckInterval = time.Second
+	}
+	loader := &templateLoader{conf: conf}
+	if err := loader.load(); err != nil {
+		return HTMLRender{}, err
+	}
+	return HTMLRender{Template: loader.base, loader: loader}, nil
+}
+
+// Lookup 返回页面所在的模板集合和需要执行的模板名
+func (r HTMLRender) Lookup(name string) (*template.Template, string, error) {
+	if r.loader != nil {
+		return r.loader.lookup(name)
+	}
+	if r.Template == nil {
+		return nil, "", errors.New("html template is not loaded")
+	}
+	return r.Template, name, nil
+}
+
+type page struct {
+	template *template.Template
+	name     string
+}
+
+type templateLoader struct {
+	conf      TemplateConfig
+	mu        sync.RWMutex
+	shared    *template.Template
+	base      *template.Template
+	pages     map[string]page
+	signature string
+	checked   time.Time
+}
+
+func (l *templateLoader) lookup(name string) (*template.Template, string, error) {
+	if l.conf.Dev {
+		if err := l.reloadIfChanged(); err != nil {
+			return nil, "", err
+		}
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.shared != nil {
+		return l.shared, name, nil
+	}
+	p, ok := l.pages[name]
+	if !ok {
+		return nil, "", fmt.Errorf("html template %s not found", name)
+	}
+	if l.conf.Layout != "" {
+		return p.template, l.conf.Layout, nil
+	}
+	return p.template, p.name, nil
+}
+
+// reloadIfChanged 最多每 CheckInterval 检查一次 避免每次渲染都匹配文件和读取文件信息
+func (l *templateLoader) reloadIfChanged() error {
+	now := time.Now()
+	l.mu.Lock()
+	if now.Sub(l.checked) < l.conf.CheckInterval {
+		l.mu.Unlock()
+		return nil
+	}
+	l.checked = now
+	l.mu.Unlock()
+	pages, layouts, err := l.files()
+	if err != nil {
+		return err
+	}
+	signature, err := l.sign(append(layouts, pages...))
+	if err != nil {
+		return err
+	}
+	l.mu.RLock()
+	changed := signature != l.signature
+	l.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return l.load()
+}
+
+func (l *templateLoader) load() error {
+	pages, layouts, err := l.files()
+	if err != nil {
+		return err
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("html template: pattern matches no files: %v", l.conf.Patterns)
+	}
+	signature, err := l.sign(append(layouts, pages...))
+	if err != nil {
+		return err
+	}
+	base := template.New("").Funcs(l.conf.FuncMap)
+	if len(layouts) == 0 {
+		if err := l.parse(base, pages...); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		l.shared, l.base, l.pages, l.signature = base, base, nil, signature
+		l.checked = time.Now()
+		l.mu.Unlock()
+		return nil
+	}
+	if err := l.parse(base, layouts...); err != nil {
+		return err
+	}
+	//base 只用来 Clone 另外保留一份给 HTMLRender.Template 读取
+	layoutSet, err := base.Clone()
+	if err != nil {
+		return err
+	}
+	result := make(map[string]page, len(pages)*2)
+	names := make(map[string]int)
+	for _, file := range pages {
+		t, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err := l.parse(t, file); err != nil {
+			return err
+		}
+		p := page{template: t, name: path.Base(file)}
+		result[file] = p
+		names[p.name]++
+		result[p.name] = p
+	}
+	//不同目录下的同名文件只能用完整路径查找
+	for name, count := range names {
+		if count > 1 {
+			delete(result, name)
+		}
+	}
+	l.mu.Lock()
+	l.shared, l.base, l.pages, l.signature = nil, layoutSet, result, signature
+	l.checked = time.Now()
+	l.mu.Unlock()
+	return nil
+}
+
+// files 按 Patterns 和 Layouts 匹配文件 同一个文件只出现一次 布局文件不作为页面
+func (l *templateLoader) files() (pages []string, layouts []string, err error) {
+	layouts, err = l.glob(l.conf.Layouts)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := l.glob(l.conf.Patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	isLayout := make(map[string]bool, len(layouts))
+	for _, file := range layouts {
+		isLayout[file] = true
+	}
+	for _, file := range all {
+		if !isLayout[file] {
+			pages = append(pages, file)
+		}
+	}
+	return pages, layouts, nil
+}
+
+func (l *templateLoader) glob(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, pattern := range patterns {
+		var matches []string
+		var err error
+		if l.conf.FS != nil {
+			matches, err = fs.Glob(l.conf.FS, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range matches {
+			file = filepath.ToSlash(file)
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// parse 模板名使用文件名 和 ParseFiles 一致
+func (l *templateLoader) parse(t *template.Template, files ...string) error {
+	for _, file := range files {
+		var content []byte
+		var err error
+		if l.conf.FS != nil {
+			content, err = fs.ReadFile(l.conf.FS, file)
+		} else {
+			content, err = os.ReadFile(filepath.FromSlash(file))
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := t.New(path.Base(file)).Parse(string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sign 文件列表 修改时间和大小 任意一个变化都需要重新解析
+func (l *templateLoader) sign(files []string) (string, error) {
+	var b strings.Builder
+	for _, file := range files {
+		var info fs.FileInfo
+		var err error
+		if l.conf.FS != nil {
+			info, err = fs.Stat(l.conf.FS, file)
+		} else {
+			info, err = os.Stat(filepath.FromSlash(file))
+		}
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,92 @@
+package msgo
+
+import (
+	"bytes"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderers(t *testing.T) {
+	type Goods struct {
+		Name string `json:"name" yaml:"name" msgpack:"name"`
+	}
+	goods := Goods{Name: "<b>phone</b>"}
+	engine := New()
+	g := engine.Group("goods")
+	g.Get("/pure", func(ctx *Context) { _ = ctx.PureJSON(http.StatusOK, goods) })
+	g.Get("/indented", func(ctx *Context) { _ = ctx.IndentedJSON(http.StatusOK, goods) })
+	g.Get("/jsonp", func(ctx *Context) { _ = ctx.JSONP(http.StatusOK, goods) })
+	g.Get("/yaml", func(ctx *Context) { _ = ctx.YAML(http.StatusOK, goods) })
+	g.Get("/negotiate", func(ctx *Context) { _ = ctx.Negotiate(http.StatusOK, wrapperspb.String("phone")) })
+
+	tests := []struct {
+		path, accept, contentType, body string
+	}{
+		{"/goods/pure", "", "application/json; charset=utf-8", `{"name":"<b>phone</b>"}`},
+		{"/goods/indented", "", "application/json; charset=utf-8", "{\n    \"name\": \"\\u003cb\\u003ephone\\u003c/b\\u003e\"\n}"},
+		{"/goods/jsonp?callback=jQuery1.cb", "", "application/javascript; charset=utf-8", `/**/jQuery1.cb({"name":"\u003cb\u003ephone\u003c/b\u003e"});`},
+		{"/goods/jsonp?callback=alert(1)//", "", "application/json; charset=utf-8", `{"name":"\u003cb\u003ephone\u003c/b\u003e"}`},
+		{"/goods/yaml", "", "application/x-yaml; charset=utf-8", "name: <b>phone</b>\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s: content type = %q, body = %q", tt.path, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/goods/negotiate", nil)
+	r.Header.Set("Accept", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	message := &wrapperspb.StringValue{}
+	if err := proto.Unmarshal(w.Body.Bytes(), message); err != nil || message.Value != "phone" {
+		t.Errorf("protobuf: value = %q, err = %v", message.Value, err)
+	}
+}
+
+func TestBindFormats(t *testing.T) {
+	type Goods struct {
+		Name  string `yaml:"name" msgpack:"name" validate:"required"`
+		Price int64  `yaml:"price" msgpack:"price"`
+	}
+	engine := New()
+	var goods Goods
+	var protoGoods wrapperspb.StringValue
+	g := engine.Group("goods")
+	g.Post("/add", func(ctx *Context) {
+		goods = Goods{}
+		if err := ctx.Bind(&goods); err != nil {
+			t.Error(err)
+		}
+	})
+	g.Post("/proto", func(ctx *Context) {
+		if err := ctx.BindProtoBuf(&protoGoods); err != nil {
+			t.Error(err)
+		}
+	})
+
+	yamlBody, _ := yaml.Marshal(Goods{Name: "phone", Price: 1 << 60})
+	msgpackBody, _ := msgpack.Marshal(Goods{Name: "phone", Price: 1 << 60})
+	for contentType, body := range map[string][]byte{"application/x-yaml": yamlBody, "application/msgpack": msgpackBody} {
+		r := httptest.NewRequest(http.MethodPost, "/goods/add", bytes.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		engine.ServeHTTP(httptest.NewRecorder(), r)
+		if goods.Name != "phone" || goods.Price != 1<<60 {
+			t.Errorf("%s: goods = %+v", contentType, goods)
+		}
+	}
+
+	body, _ := proto.Marshal(wrapperspb.String("phone"))
+	r := httptest.NewRequest(http.MethodPost, "/goods/proto", bytes.NewReader(body))
+	engine.ServeHTTP(httptest.NewRecorder(), r)
+	if protoGoods.Value != "phone" {
+		t.Errorf("protobuf: value = %q", protoGoods.Value)
+	}
+}
@@ -0,0 +1,57 @@
+package msgo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	msLog "github.com/mszlu521/msgo/log"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// RequestID 使用请求头中的 X-Request-ID 没有或者不合法时生成一个 并在响应头中返回
+// ctx.Logger 换成带有 request_id 的子 logger ctx.R.Context() 中也带有 request_id
+// 把 ctx.R.Context() 传给 orm rpc 等 它们的日志中会有同样的 request_id
+func RequestID(next HandlerFunc) HandlerFunc {
+	return func(ctx *Context) {
+		id := ctx.R.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		ctx.W.Header().Set(HeaderRequestID, id)
+		ctx.withLogFields(msLog.Fields{"request_id": id})
+		next(ctx)
+	}
+}
+
+// RequestID RequestID 中间件设置的请求 id
+func (c *Context) RequestID() string {
+	id, _ := msLog.FieldsFromContext(c.R.Context())["request_id"].(string)
+	return id
+}
+
+// withLogFields 字段同时加到 ctx.Logger 和 ctx.R.Context() 中
+func (c *Context) withLogFields(fields msLog.Fields) {
+	c.R = c.R.WithContext(msLog.ContextWithFields(c.R.Context(), fields))
+	if c.Logger != nil {
+		c.Logger = c.Logger.WithFields(fields)
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID 客户端传来的 id 会写入日志 只接受不太长的可见字符
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package msgo
+
+import (
+	"bytes"
+	"encoding/json"
+	msLog "github.com/mszlu521/msgo/log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	engine := New()
+	engine.Logger = msLog.New()
+	engine.Logger.Formatter = &msLog.JsonFormatter{}
+	engine.Logger.Outs = append(engine.Logger.Outs, &msLog.LoggerWriter{Level: msLog.LevelAll, Out: &buf})
+	engine.Use(RequestID)
+	var fromContext msLog.Fields
+	engine.Group("").Get("/id", func(ctx *Context) {
+		ctx.Logger.Info("handle")
+		fromContext = msLog.FieldsFromContext(ctx.R.Context())
+		ctx.String(http.StatusOK, ctx.RequestID())
+	})
+
+	tests := []struct {
+		header string
+		reuse  bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"bad\nid", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		r := httptest.NewRequest(http.MethodGet, "/id", nil)
+		if tt.header != "" {
+			r.Header.Set(HeaderRequestID, tt.header)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		id := w.Header().Get(HeaderRequestID)
+		if tt.reuse != (id == tt.header) || id == "" || w.Body.String() != id {
+			t.Errorf("header %q: response id %q body %q", tt.header, id, w.Body.String())
+		}
+		if fromContext["request_id"] != id {
+			t.Errorf("context fields = %v", fromContext)
+		}
+		line := make(map[string]any)
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil || line["request_id"] != id {
+			t.Errorf("log line %s: %v", buf.String(), err)
+		}
+	}
+	//子 logger 不影响 engine.Logger
+	if len(engine.Logger.LoggerFields) != 0 {
+		t.Errorf("engine logger fields = %v", engine.Logger.LoggerFields)
+	}
+}
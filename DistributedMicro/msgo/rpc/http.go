@@ -3,11 +3,12 @@ package rpc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	msLog "github.com/mszlu521/msgo/log"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -18,6 +19,7 @@ import (
 type MsHttpClient struct {
 	client     http.Client
 	serviceMap map[string]MsService
+	Logger     *msLog.Logger
 }
 
 func NewHttpClient() *MsHttpClient {
@@ -32,7 +34,7 @@ func NewHttpClient() *MsHttpClient {
 			ExpectContinueTimeout: 1 * time.Second,
 		},
 	}
-	return &MsHttpClient{client: client, serviceMap: make(map[string]MsService)}
+	return &MsHttpClient{client: client, serviceMap: make(map[string]MsService), Logger: msLog.Default()}
 }
 
 func (c *MsHttpClient) GetRequest(method string, url string, args map[string]any) (*http.Request, error) {
@@ -72,8 +74,8 @@ func (c *MsHttpClientSession) Get(url string, args map[string]any) ([]byte, erro
 	if args != nil && len(args) > 0 {
 		url = url + "?" + c.toValues(args)
 	}
-	log.Println(url)
-	request, err := http.NewRequest("GET", url, nil)
+	c.logger().Info("GET " + url)
+	request, err := http.NewRequestWithContext(c.context(), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +84,7 @@ func (c *MsHttpClientSession) Get(url string, args map[string]any) ([]byte, erro
 }
 
 func (c *MsHttpClientSession) PostForm(url string, args map[string]any) ([]byte, error) {
-	request, err := http.NewRequest("POST", url, strings.NewReader(c.toValues(args)))
+	request, err := http.NewRequestWithContext(c.context(), "POST", url, strings.NewReader(c.toValues(args)))
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +93,7 @@ func (c *MsHttpClientSession) PostForm(url string, args map[string]any) ([]byte,
 
 func (c *MsHttpClientSession) PostJson(url string, args map[string]any) ([]byte, error) {
 	marshal, _ := json.Marshal(args)
-	request, err := http.NewRequest("POST", url, bytes.NewReader(marshal))
+	request, err := http.NewRequestWithContext(c.context(), "POST", url, bytes.NewReader(marshal))
 	if err != nil {
 		return nil, err
 	}
@@ -99,13 +101,21 @@ func (c *MsHttpClientSession) PostJson(url string, args map[string]any) ([]byte,
 }
 
 func (c *MsHttpClientSession) responseHandle(request *http.Request) ([]byte, error) {
-	c.ReqHandler(request)
+	//请求 id 传给下游服务
+	if id, ok := msLog.FieldsFromContext(request.Context())["request_id"].(string); ok && request.Header.Get("X-Request-ID") == "" {
+		request.Header.Set("X-Request-ID", id)
+	}
+	if c.ReqHandler != nil {
+		c.ReqHandler(request)
+	}
 	response, err := c.client.Do(request)
 	if err != nil {
+		c.logger().Error(fmt.Sprintf("%s %s: %v", request.Method, request.URL, err))
 		return nil, err
 	}
 	if response.StatusCode != http.StatusOK {
 		info := fmt.Sprintf("response status is %d", response.StatusCode)
+		c.logger().Error(fmt.Sprintf("%s %s: %s", request.Method, request.URL, info))
 		return nil, errors.New(info)
 	}
 	reader := bufio.NewReader(response.Body)
@@ -165,6 +175,28 @@ type MsService interface {
 type MsHttpClientSession struct {
 	*MsHttpClient
 	ReqHandler func(req *http.Request)
+	ctx        context.Context
+}
+
+// WithContext 请求使用 ctx 日志带上 ctx 中的 request_id trace_id 等字段 request_id 通过 X-Request-ID 传给下游
+func (c *MsHttpClientSession) WithContext(ctx context.Context) *MsHttpClientSession {
+	c.ctx = ctx
+	return c
+}
+
+func (c *MsHttpClientSession) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *MsHttpClientSession) logger() *msLog.Logger {
+	logger := c.Logger
+	if logger == nil {
+		logger = msLog.Default()
+	}
+	return logger.WithContext(c.ctx)
 }
 
 func (c *MsHttpClient) RegisterHttpService(name string, service MsService) {
@@ -173,7 +205,7 @@ func (c *MsHttpClient) RegisterHttpService(name string, service MsService) {
 
 func (c *MsHttpClient) Session() *MsHttpClientSession {
 	return &MsHttpClientSession{
-		c, nil,
+		MsHttpClient: c,
 	}
 }
 func (c *MsHttpClientSession) Do(service string, method string) MsService {
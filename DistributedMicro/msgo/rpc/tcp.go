@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	msLog "github.com/mszlu521/msgo/log"
 	"github.com/mszlu521/msgo/register"
 	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/proto"
@@ -570,6 +571,7 @@ type MsTcpClient struct {
 	option      TcpClientOption
 	ServiceName string
 	RegisterCli register.MsRegister
+	Logger      *msLog.Logger
 }
 type TcpClientOption struct {
 	Retries           int
@@ -593,7 +595,7 @@ var DefaultOption = TcpClientOption{
 }
 
 func NewTcpClient(option TcpClientOption) *MsTcpClient {
-	return &MsTcpClient{option: option}
+	return &MsTcpClient{option: option, Logger: msLog.Default()}
 }
 
 func (c *MsTcpClient) Connect() error {
@@ -691,22 +693,30 @@ func (c *MsTcpClient) Invoke(ctx context.Context, serviceName string, methodName
 		return nil, err
 	}
 	rspChan := make(chan *MsRpcResponse)
-	go c.readHandle(rspChan)
+	go c.readHandle(ctx, rspChan)
 	rsp := <-rspChan
 	return rsp, nil
 }
 
-func (c *MsTcpClient) readHandle(rspChan chan *MsRpcResponse) {
+// logger 日志带上 ctx 中的 request_id trace_id 等字段
+func (c *MsTcpClient) logger(ctx context.Context) *msLog.Logger {
+	if c.Logger == nil {
+		return msLog.Default().WithContext(ctx)
+	}
+	return c.Logger.WithContext(ctx)
+}
+
+func (c *MsTcpClient) readHandle(ctx context.Context, rspChan chan *MsRpcResponse) {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Println("MsTcpClient readHandle recover: ", err)
+			c.logger(ctx).Errorf("MsTcpClient readHandle recover: %v", err)
 			c.conn.Close()
 		}
 	}()
 	for {
 		msg, err := decodeFrame(c.conn)
 		if err != nil {
-			log.Println("未解析出任何数据")
+			c.logger(ctx).Errorw("未解析出任何数据", "service", c.ServiceName, "error", err.Error())
 			rsp := &MsRpcResponse{}
 			rsp.Code = 500
 			rsp.Msg = err.Error()
@@ -835,7 +845,7 @@ func (p *MsTcpClientProxy) Call(ctx context.Context, serviceName string, methodN
 		result, err := client.Invoke(ctx, serviceName, methodName, args)
 		if err != nil {
 			if i >= p.option.Retries-1 {
-				log.Println(errors.New("already retry all time"))
+				client.logger(ctx).Errorw("already retry all time", "service", serviceName, "method", methodName, "error", err.Error())
 				client.Close()
 				return nil, err
 			}
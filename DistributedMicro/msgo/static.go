@@ -0,0 +1,124 @@
+package msgo
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+const defaultIndexFile = "index.html"
+
+type StaticConfig struct {
+	Root http.FileSystem
+	//目录下的默认文件 默认 index.html
+	Index string
+	//找不到文件时返回根目录的 Index 单页应用的前端路由由浏览器处理
+	SPA bool
+	//是否允许列出目录 默认不允许
+	Browse bool
+}
+
+// Static 挂载本地目录 /assets -> ./static
+func (r *routerGroup) Static(prefix string, root string) {
+	r.StaticFS(prefix, http.Dir(root))
+}
+
+// StaticFS 挂载文件系统 embed.FS 可以使用 http.FS(fs) 转换
+func (r *routerGroup) StaticFS(prefix string, fs http.FileSystem) {
+	r.StaticWithConfig(prefix, StaticConfig{Root: fs})
+}
+
+func (r *routerGroup) StaticWithConfig(prefix string, conf StaticConfig) {
+	if conf.Index == "" {
+		conf.Index = defaultIndexFile
+	}
+	handler := (&staticHandler{conf: conf}).handle
+	prefix = strings.TrimSuffix(prefix, "/")
+	//基于 ** 通配符 /assets/**filepath
+	r.Get(prefix+"/**filepath", handler)
+	//前缀为空时也注册 请求分组根路径 /admin 时返回 index
+	r.Get(prefix, handler)
+}
+
+type staticHandler struct {
+	conf  StaticConfig
+	etags sync.Map
+}
+
+func (s *staticHandler) handle(ctx *Context) {
+	name := path.Clean("/" + ctx.Param("filepath"))
+	if s.serveFile(ctx, name) {
+		return
+	}
+	if s.conf.SPA && s.serveFile(ctx, "/"+s.conf.Index) {
+		return
+	}
+	ctx.String(http.StatusNotFound, "%s  not found \n", ctx.R.RequestURI)
+}
+
+// serveFile 文件不存在或者是不允许展示的目录时返回 false
+func (s *staticHandler) serveFile(ctx *Context, name string) bool {
+	f, err := s.conf.Root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if stat.IsDir() {
+		index := path.Join(name, s.conf.Index)
+		if s.serveFile(ctx, index) {
+			return true
+		}
+		if !s.conf.Browse {
+			return false
+		}
+		http.FileServer(s.conf.Root).ServeHTTP(ctx.W, s.dirRequest(ctx, name))
+		return true
+	}
+	etag, err := s.etag(name, stat, f)
+	if err != nil {
+		return false
+	}
+	ctx.W.Header().Set("ETag", etag)
+	//ServeContent 处理 If-None-Match If-Modified-Since 和 Range
+	http.ServeContent(ctx.W, ctx.R, stat.Name(), stat.ModTime(), f)
+	return true
+}
+
+// etag 有修改时间时使用修改时间和大小 embed.FS 没有修改时间 使用内容的摘要并缓存
+func (s *staticHandler) etag(name string, stat os.FileInfo, f http.File) (string, error) {
+	if !stat.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()), nil
+	}
+	if v, ok := s.etags.Load(name); ok {
+		return v.(string), nil
+	}
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := fmt.Sprintf(`"%x"`, h.Sum(nil))
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+// dirRequest FileServer 按请求路径查找文件 改成相对于 Root 的路径
+func (s *staticHandler) dirRequest(ctx *Context, name string) *http.Request {
+	r := ctx.R.Clone(ctx.R.Context())
+	if !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+	r.URL.Path = name
+	return r
+}
@@ -0,0 +1,62 @@
+package msgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticFS(t *testing.T) {
+	fs := fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>admin</h1>")},
+		"js/app.js":     {Data: []byte("console.log(1)")},
+		"docs/note.txt": {Data: []byte("note")},
+	}
+	engine := New()
+	admin := engine.Group("admin")
+	admin.StaticWithConfig("/", StaticConfig{Root: http.FS(fs), SPA: true})
+	assets := engine.Group("assets")
+	assets.StaticFS("/", http.FS(fs))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/assets/js/app.js", http.StatusOK, "console.log(1)"},
+		{"/assets/", http.StatusOK, "<h1>admin</h1>"},
+		{"/assets/docs/", http.StatusNotFound, ""},
+		{"/assets/none.js", http.StatusNotFound, ""},
+		{"/assets/../../etc/passwd", http.StatusNotFound, ""},
+		{"/admin/users/1", http.StatusOK, "<h1>admin</h1>"},
+		//分组根路径也要返回 index
+		{"/admin", http.StatusOK, "<h1>admin</h1>"},
+		{"/assets", http.StatusOK, "<h1>admin</h1>"},
+		{"/admin/js/app.js", http.StatusOK, "console.log(1)"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/js/app.js", nil))
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/assets/js/app.js", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: code = %d, want 304", w.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package msgo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mszlu521/msgo/render"
+)
+
+func TestSSE(t *testing.T) {
+	engine := New()
+	engine.Group("order").Get("/status", func(ctx *Context) {
+		_ = ctx.SSEvent(&render.SSEvent{Id: "1", Event: "paid", Retry: 3000, Data: "line1\nline2"})
+		_ = ctx.SSE("shipped", map[string]any{"id": 1})
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order/status", nil))
+	want := "id:1\nevent:paid\nretry:3000\ndata:line1\ndata:line2\n\n" +
+		"event:shipped\ndata:{\"id\":1}\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+	if w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Errorf("header = %v, flushed = %v", w.Header(), w.Flushed)
+	}
+}
+
+func TestStream(t *testing.T) {
+	engine := New()
+	c, cancel := context.WithCancel(context.Background())
+	var gone bool
+	engine.Group("order").Get("/stream", func(ctx *Context) {
+		i := 0
+		gone = ctx.Stream(func(w io.Writer) bool {
+			i++
+			fmt.Fprintf(w, "%d\n", i)
+			if i == 3 {
+				//客户端断开
+				cancel()
+			}
+			return i < 10
+		})
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order/stream", nil).WithContext(c))
+	if !gone || w.Body.String() != "1\n2\n3\n" {
+		t.Errorf("gone = %v, body = %q", gone, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/order/stream", nil))
+	if gone || w.Body.String() != "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n" {
+		t.Errorf("gone = %v, body = %q", gone, w.Body.String())
+	}
+}
@@ -0,0 +1,118 @@
+package msgo
+
+import (
+	"github.com/mszlu521/msgo/render"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTemplateLayout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tpl/layout/base.html":   {Data: []byte(`<title>{{block "title" .}}msgo{{end}}</title><body>{{template "header.html" .}}{{block "content" .}}{{end}}</body>`)},
+		"tpl/layout/header.html": {Data: []byte(`<h1>{{upper .Site}}</h1>`)},
+		"tpl/index.html":         {Data: []byte(`{{define "content"}}index {{.Name}}{{end}}`)},
+		"tpl/user/login.html":    {Data: []byte(`{{define "title"}}login{{end}}{{define "content"}}login {{.Name}}{{end}}`)},
+		"tpl/user/broken.html":   {Data: []byte(`{{define "content"}}{{index .Name 99}}{{end}}`)},
+	}
+	engine := New()
+	engine.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	err := engine.LoadTemplateWithConfig(render.TemplateConfig{
+		FS:       fsys,
+		Patterns: []string{"tpl/*.html", "tpl/user/*.html"},
+		Layouts:  []string{"tpl/layout/*.html"},
+		Layout:   "base.html",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//有布局时 Template 是布局和公共片段
+	if engine.HTMLRender.Template == nil || engine.HTMLRender.Template.Lookup("base.html") == nil {
+		t.Error("HTMLRender.Template does not hold the layouts")
+	}
+	data := map[string]any{"Site": "shop", "Name": "msgo"}
+	engine.Group("page").Get("/:name", func(ctx *Context) {
+		if err := ctx.TemplateStatus(http.StatusAccepted, ctx.Param("name")+".html", data); err != nil {
+			ctx.String(http.StatusInternalServerError, "render failed")
+		}
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/page/index", http.StatusAccepted, "<title>msgo</title><body><h1>SHOP</h1>index msgo</body>"},
+		{"/page/login", http.StatusAccepted, "<title>login</title><body><h1>SHOP</h1>login msgo</body>"},
+		//执行失败时没有写出任何内容 还可以返回 500
+		{"/page/broken", http.StatusInternalServerError, "render failed"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s: code = %d, body = %q", tt.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestTemplateDev(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.html")
+	write := func(content string, mod time.Time) {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(file, mod, mod)
+	}
+	write("v1 {{.}}", time.Now().Add(-time.Hour))
+
+	for _, dev := range []bool{false, true} {
+		engine := New()
+		engine.TemplateDev = dev
+		err := engine.LoadTemplateWithConfig(render.TemplateConfig{
+			Patterns:      []string{filepath.Join(dir, "*.html")},
+			CheckInterval: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		engine.Group("page").Get("/index", func(ctx *Context) {
+			_ = ctx.Template("index.html", "msgo")
+		})
+		engine.Group("file").Get("/index", func(ctx *Context) {
+			_ = ctx.HTMLTemplate("index.html", "msgo", file)
+		})
+		get := func(path string) string {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w.Body.String()
+		}
+		if engine.HTMLRender.Template == nil || engine.HTMLRender.Template.Lookup("index.html") == nil {
+			t.Errorf("dev = %v: HTMLRender.Template is not populated", dev)
+		}
+		if get("/page/index") != "v1 msgo" || get("/file/index") != "v1 msgo" {
+			t.Fatalf("dev = %v: initial render failed", dev)
+		}
+		write("v2 {{.}}", time.Now())
+		//超过检查间隔之后才会重新解析
+		time.Sleep(5 * time.Millisecond)
+		want := "v1 msgo"
+		if dev {
+			want = "v2 msgo"
+		}
+		if body := get("/page/index"); body != want {
+			t.Errorf("dev = %v: Template = %q, want %q", dev, body, want)
+		}
+		if body := get("/file/index"); body != want {
+			t.Errorf("dev = %v: HTMLTemplate = %q, want %q", dev, body, want)
+		}
+		write("v1 {{.}}", time.Now().Add(-time.Hour))
+	}
+}
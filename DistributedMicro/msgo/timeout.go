@@ -0,0 +1,145 @@
+package msgo
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout 限制处理函数的执行时间 超时后取消 ctx.R.Context() 并返回 503
+// 处理函数在单独的协程中执行 使用复制的 Context 响应先写入缓冲 按时完成时才写给客户端 所以不支持 Stream 和 WebSocket
+// 超时后处理函数应该检查 ctx.R.Context().Done() 尽快返回 之后的写入返回 http.ErrHandlerTimeout
+func Timeout(timeout time.Duration) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			c, cancel := context.WithTimeout(ctx.R.Context(), timeout)
+			defer cancel()
+			tw := &timeoutWriter{ctx: c, header: make(http.Header)}
+			//处理函数的协程只使用 hc 超时后不会再碰到 ctx
+			hc := ctx.copyFor(tw, ctx.R.WithContext(c))
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+					}
+				}()
+				next(hc)
+				close(done)
+			}()
+			select {
+			case p := <-panicChan:
+				//交给外层的 Recovery 处理
+				panic(p)
+			case <-done:
+				//超时后的写入已经被拒绝 只有按时完成的响应才写出
+				if c.Err() == nil {
+					ctx.copyBack(hc)
+					tw.writeTo(ctx.W)
+					return
+				}
+			case <-c.Done():
+			}
+			tw.timeout()
+			ctx.W.WriteHeader(http.StatusServiceUnavailable)
+			ctx.StatusCode = http.StatusServiceUnavailable
+			_, _ = ctx.W.Write([]byte(http.StatusText(http.StatusServiceUnavailable)))
+		}
+	}
+}
+
+// copyFor 处理函数使用的 Context 写入 w 其他的状态从 c 复制
+func (c *Context) copyFor(w http.ResponseWriter, r *http.Request) *Context {
+	hc := &Context{engine: c.engine}
+	hc.reset(w, r)
+	hc.params = append(hc.params, c.params...)
+	hc.DisallowUnknownFields = c.DisallowUnknownFields
+	hc.IsValidate = c.IsValidate
+	hc.Logger = c.Logger
+	hc.sameSite = c.sameSite
+	hc.uploadPolicy = c.uploadPolicy
+	c.mu.RLock()
+	if c.Keys != nil {
+		hc.Keys = make(map[string]any, len(c.Keys))
+		for k, v := range c.Keys {
+			hc.Keys[k] = v
+		}
+	}
+	c.mu.RUnlock()
+	return hc
+}
+
+// copyBack 处理函数按时完成后 把它设置的值带回 ctx 外层的中间件可以拿到
+func (c *Context) copyBack(hc *Context) {
+	c.mu.Lock()
+	c.Keys = hc.Keys
+	c.mu.Unlock()
+	c.StatusCode = hc.StatusCode
+	c.Logger = hc.Logger
+	if hc.aborted {
+		c.aborted = true
+	}
+}
+
+// timeoutWriter 缓存处理函数的响应 超时后不再接受写入
+type timeoutWriter struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+}
+
+// timeout 之后处理函数的写入都被丢弃
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	tw.timedOut = true
+	tw.mu.Unlock()
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil || tw.written {
+		return
+	}
+	tw.status = code
+	tw.written = true
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.written {
+		tw.status = http.StatusOK
+		tw.written = true
+	}
+	return tw.buf.Write(b)
+}
+
+// Flush 响应在处理函数返回后才写出
+func (tw *timeoutWriter) Flush() {}
+
+func (tw *timeoutWriter) writeTo(w http.ResponseWriter) {
+	dst := w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if !tw.written {
+		return
+	}
+	w.WriteHeader(tw.status)
+	_, _ = w.Write(tw.buf.Bytes())
+}
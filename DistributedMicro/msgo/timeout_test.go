@@ -0,0 +1,144 @@
+package msgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodyLimit(t *testing.T) {
+	engine := New()
+	g := engine.Group("limit")
+	g.Post("/json", func(ctx *Context) {
+		var v map[string]any
+		if ctx.BindJson(&v) == nil {
+			ctx.String(http.StatusOK, "ok")
+		}
+	}, BodyLimit(16))
+
+	tests := []struct {
+		body          string
+		contentLength int64
+		code          int
+	}{
+		{`{"a":1}`, 7, http.StatusOK},
+		{`{"name":"` + strings.Repeat("a", 32) + `"}`, 42, http.StatusRequestEntityTooLarge},
+		//chunked 没有 Content-Length 读取时才发现超过限制
+		{`{"name":"` + strings.Repeat("a", 32) + `"}`, -1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/limit/json", strings.NewReader(tt.body))
+		r.ContentLength = tt.contentLength
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("content length %d: code = %d, want %d", tt.contentLength, w.Code, tt.code)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	engine := New()
+	canceled := make(chan bool, 1)
+	g := engine.Group("timeout")
+	g.Get("/slow", func(ctx *Context) {
+		select {
+		case <-ctx.R.Context().Done():
+			_, err := ctx.W.Write([]byte("late"))
+			canceled <- err == http.ErrHandlerTimeout
+		case <-time.After(time.Second):
+			canceled <- false
+		}
+	}, Timeout(20*time.Millisecond))
+	g.Get("/fast", func(ctx *Context) {
+		ctx.W.Header().Set("X-Fast", "1")
+		ctx.String(http.StatusCreated, "fast")
+	}, Timeout(time.Second))
+	g.Get("/panic", func(ctx *Context) {
+		panic(http.ErrAbortHandler)
+	}, Timeout(time.Second))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/timeout/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("slow code = %d", w.Code)
+	}
+	if !<-canceled {
+		t.Error("handler context is not canceled or write after timeout succeeded")
+	}
+	if strings.Contains(w.Body.String(), "late") {
+		t.Errorf("slow body = %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/timeout/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Errorf("fast = %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("panic = %v", p)
+		}
+	}()
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/timeout/panic", nil))
+}
+
+func TestServerTimeout(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		conf any
+		want time.Duration
+	}{
+		{time.Second, "5s", time.Second},
+		{0, "1m30s", 90 * time.Second},
+		{0, int64(3), 3 * time.Second},
+		{0, 0.5, 500 * time.Millisecond},
+		{0, "bad", 0},
+		{0, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := serverTimeout(tt.d, tt.conf); got != tt.want {
+			t.Errorf("serverTimeout(%v, %v) = %v, want %v", tt.d, tt.conf, got, tt.want)
+		}
+	}
+}
+
+// TestTimeoutLateRender 超时后处理函数仍然渲染响应和修改 ctx 需要在 -race 下运行
+func TestTimeoutLateRender(t *testing.T) {
+	engine := New()
+	finished := make(chan error, 1)
+	var outer *Context
+	engine.Group("").Get("/late", func(ctx *Context) {
+		<-ctx.R.Context().Done()
+		time.Sleep(5 * time.Millisecond)
+		ctx.Set("late", true)
+		finished <- ctx.String(http.StatusOK, "late")
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			outer = ctx
+			next(ctx)
+			//外层中间件在处理函数还在运行时读取 ctx
+			_ = ctx.Writer().Status()
+			_, _ = ctx.Get("late")
+		}
+	}, Timeout(10*time.Millisecond))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/late", nil))
+	//下一个请求复用池中的 Context
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/late", nil))
+	for i := 0; i < 2; i++ {
+		if err := <-finished; err != http.ErrHandlerTimeout {
+			t.Errorf("late render err = %v", err)
+		}
+	}
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "late") {
+		t.Errorf("response = %d %q", w.Code, w.Body.String())
+	}
+	if _, ok := outer.Get("late"); ok {
+		t.Error("late handler changed the request context")
+	}
+}
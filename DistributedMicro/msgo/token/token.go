@@ -252,8 +252,9 @@ func (j *JwtHandler) AuthInterceptor(next msgo.HandlerFunc) msgo.HandlerFunc {
 }
 
 func (j *JwtHandler) AuthErrorHandler(ctx *msgo.Context, err error) {
+	ctx.Abort()
 	if j.AuthHandler == nil {
-		ctx.W.WriteHeader(http.StatusUnauthorized)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 	} else {
 		j.AuthHandler(ctx, err)
 	}
@@ -1,9 +1,11 @@
 package msgo
 
 import (
+	msLog "github.com/mszlu521/msgo/log"
 	tracer2 "github.com/mszlu521/msgo/tracer"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
+	"github.com/uber/jaeger-client-go"
 	"github.com/uber/jaeger-client-go/config"
 )
 
@@ -30,9 +32,16 @@ func Tracer(serviceName string, samplerConfig *config.SamplerConfig, reporter *c
 
 			// 在 header 中加上当前进程的上下文信息
 			ctx.R = ctx.R.WithContext(opentracing.ContextWithSpan(ctx.R.Context(), startSpan))
+			// 日志中带上 trace_id 和 span_id
+			if spanContext, ok := startSpan.Context().(jaeger.SpanContext); ok {
+				ctx.withLogFields(msLog.Fields{
+					"trace_id": spanContext.TraceID().String(),
+					"span_id":  spanContext.SpanID().String(),
+				})
+			}
 			next(ctx)
 			// 继续设置 tag
-			ext.HTTPStatusCode.Set(startSpan, uint16(ctx.StatusCode))
+			ext.HTTPStatusCode.Set(startSpan, uint16(ctx.Writer().Status()))
 		}
 	}
 }
@@ -1,84 +1,277 @@
 package msgo
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
+type nodeType uint8
+
+const (
+	static   nodeType = iota
+	param             // :id 匹配一段路径
+	wildcard          // * 匹配一段路径
+	catchAll          // ** 匹配剩余的所有路径
+)
+
+// Param 路由中捕获到的参数 :id -> Key: id
+type Param struct {
+	Key   string
+	Value string
+}
+
+type Params []Param
+
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
+// 压缩前缀树
+// 静态节点 path 是公共前缀，可能跨越多段路径 如 /user/get/
+// 参数节点 path 是注册时的整段 如 :id * **
+// 匹配优先级 静态 > :id > * > **，wildChildren 按这个顺序排列，每种最多一个
 type treeNode struct {
-	name       string
-	children   []*treeNode
-	routerName string
-	isEnd      bool
+	path         string
+	nType        nodeType
+	indices      string
+	children     []*treeNode
+	wildChildren []*treeNode
+	routerName   string
+	isEnd        bool
 }
 
 //put path: /user/get/:id
 
-func (t *treeNode) Put(path string) {
-	root := t
-	strs := strings.Split(path, "/")
-	for index, name := range strs {
-		if index == 0 {
+func (t *treeNode) Put(path string) error {
+	n := t
+	pattern := path
+	for {
+		i, wild := nextWildcard(path)
+		if i < 0 {
+			n = n.putStatic(path)
+			break
+		}
+		if wild == ":" {
+			return fmt.Errorf("route [%s]: path parameter must have a name", pattern)
+		}
+		if strings.HasPrefix(wild, "**") && i+len(wild) < len(path) {
+			return fmt.Errorf("route [%s]: [%s] must be the last segment", pattern, wild)
+		}
+		n = n.putStatic(path[:i])
+		child, err := n.putWild(wild)
+		if err != nil {
+			return fmt.Errorf("route [%s] conflicts with [%s]: %w", pattern, child.anyRouterName(), err)
+		}
+		n = child
+		path = path[i+len(wild):]
+	}
+	//注册的时候记录路由 请求过来的时候只读 不会有并发写
+	n.isEnd = true
+	n.routerName = pattern
+	return nil
+}
+
+// nextWildcard 查找下一个以 : 或 * 开头的路径段
+func nextWildcard(path string) (int, string) {
+	for i := 0; i < len(path); i++ {
+		if path[i] != ':' && path[i] != '*' {
 			continue
 		}
-		children := t.children
-		isMatch := false
-		for _, node := range children {
-			if node.name == name {
-				isMatch = true
-				t = node
-				break
-			}
+		if i > 0 && path[i-1] != '/' {
+			continue
+		}
+		end := strings.IndexByte(path[i:], '/')
+		if end < 0 {
+			return i, path[i:]
+		}
+		return i, path[i : i+end]
+	}
+	return -1, ""
+}
+
+func (t *treeNode) putStatic(path string) *treeNode {
+	n := t
+	for len(path) > 0 {
+		child := n.staticChild(path[0])
+		if child == nil {
+			child = &treeNode{path: path, nType: static}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			child.split(l)
 		}
-		if !isMatch {
-			isEnd := false
-			if index == len(strs)-1 {
-				isEnd = true
+		path = path[l:]
+		n = child
+	}
+	return n
+}
+
+// split 把静态节点从 l 处拆成两个节点 原有的子节点和路由都挂到后半段
+func (t *treeNode) split(l int) {
+	rest := &treeNode{
+		path:         t.path[l:],
+		nType:        static,
+		indices:      t.indices,
+		children:     t.children,
+		wildChildren: t.wildChildren,
+		routerName:   t.routerName,
+		isEnd:        t.isEnd,
+	}
+	t.path = t.path[:l]
+	t.indices = string(rest.path[0])
+	t.children = []*treeNode{rest}
+	t.wildChildren = nil
+	t.routerName = ""
+	t.isEnd = false
+}
+
+// putWild 同一位置的同类通配符只能有一个 /user/:id 和 /user/:name 无法区分
+func (t *treeNode) putWild(wild string) (*treeNode, error) {
+	nType := param
+	if strings.HasPrefix(wild, "**") {
+		nType = catchAll
+	} else if strings.HasPrefix(wild, "*") {
+		nType = wildcard
+	}
+	index := len(t.wildChildren)
+	for i, child := range t.wildChildren {
+		if child.nType == nType {
+			if child.path != wild {
+				return child, fmt.Errorf("[%s] and [%s] match the same segment", wild, child.path)
 			}
-			node := &treeNode{name: name, children: make([]*treeNode, 0), isEnd: isEnd}
-			children = append(children, node)
-			t.children = children
-			t = node
+			return child, nil
+		}
+		if child.nType > nType {
+			index = i
+			break
 		}
 	}
-	t = root
+	child := &treeNode{path: wild, nType: nType}
+	t.wildChildren = append(t.wildChildren, nil)
+	copy(t.wildChildren[index+1:], t.wildChildren[index:])
+	t.wildChildren[index] = child
+	return child, nil
 }
 
-//get path: /user/get/1
+// anyRouterName 子树中任意一个已注册的路由 用于冲突时的提示
+func (t *treeNode) anyRouterName() string {
+	if t.isEnd {
+		return t.routerName
+	}
+	for _, child := range t.children {
+		if name := child.anyRouterName(); name != "" {
+			return name
+		}
+	}
+	for _, child := range t.wildChildren {
+		if name := child.anyRouterName(); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func (t *treeNode) staticChild(c byte) *treeNode {
+	for i := 0; i < len(t.indices); i++ {
+		if t.indices[i] == c {
+			return t.children[i]
+		}
+	}
+	return nil
+}
+
+// paramName :id -> id  *name -> name  单独的 * ** 用自身做名字
+func (t *treeNode) paramName() string {
+	switch t.nType {
+	case param:
+		return t.path[1:]
+	case wildcard:
+		if len(t.path) > 1 {
+			return t.path[1:]
+		}
+	case catchAll:
+		if len(t.path) > 2 {
+			return t.path[2:]
+		}
+	}
+	return t.path
+}
+
+// get path: /user/get/1
 // /hello
-func (t *treeNode) Get(path string) *treeNode {
-	strs := strings.Split(path, "/")
-	routerName := ""
-	for index, name := range strs {
-		if index == 0 {
-			continue
+// 匹配到的参数追加到 params 中，不修改树上的任何节点
+func (t *treeNode) Get(path string, params *Params) *treeNode {
+	if params == nil {
+		params = &Params{}
+	}
+	return t.match(path, params)
+}
+
+func (t *treeNode) match(path string, params *Params) *treeNode {
+	if path == "" {
+		if t.isEnd {
+			return t
 		}
-		children := t.children
-		isMatch := false
-		for _, node := range children {
-			if node.name == name ||
-				node.name == "*" ||
-				strings.Contains(node.name, ":") {
-				isMatch = true
-				routerName += "/" + node.name
-				node.routerName = routerName
-				t = node
-				if index == len(strs)-1 {
-					return node
-				}
-				break
+		for _, child := range t.wildChildren {
+			// /user/** 也能匹配 /user/
+			if child.nType == catchAll {
+				*params = append(*params, Param{Key: child.paramName()})
+				return child
 			}
 		}
-		if !isMatch {
-			for _, node := range children {
-				// /user/**
-				// /user/get/userInfo
-				// /user/aa/bb
-				if node.name == "**" {
-					routerName += "/" + node.name
-					node.routerName = routerName
-					return node
-				}
-			}
-
+		return nil
+	}
+	if child := t.staticChild(path[0]); child != nil && strings.HasPrefix(path, child.path) {
+		if node := child.match(path[len(child.path):], params); node != nil {
+			return node
 		}
 	}
+	for _, child := range t.wildChildren {
+		if child.nType == catchAll {
+			// /user/**
+			// /user/get/userInfo
+			// /user/aa/bb
+			*params = append(*params, Param{Key: child.paramName(), Value: path})
+			return child
+		}
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end == 0 {
+			continue
+		}
+		count := len(*params)
+		*params = append(*params, Param{Key: child.paramName(), Value: path[:end]})
+		if node := child.match(path[end:], params); node != nil {
+			return node
+		}
+		*params = (*params)[:count]
+	}
 	return nil
 }
+
+func longestCommonPrefix(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -2,23 +2,123 @@ package msgo
 
 import (
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 )
 
 func TestTreeNode(t *testing.T) {
-	root := &treeNode{name: "/", children: make([]*treeNode, 0)}
+	root := &treeNode{}
 
 	root.Put("/user/get/:id")
 	root.Put("/user/create/hello")
 	root.Put("/user/create/aaa")
 	root.Put("/order/get/aaa")
 
-	node := root.Get("/user/get/1")
+	node := root.Get("/user/get/1", nil)
 	fmt.Println(node)
-	node = root.Get("/user/create/hello")
+	node = root.Get("/user/create/hello", nil)
 	fmt.Println(node)
-	node = root.Get("/user/create/aaa")
+	node = root.Get("/user/create/aaa", nil)
 	fmt.Println(node)
-	node = root.Get("/order/get/aaa")
+	node = root.Get("/order/get/aaa", nil)
 	fmt.Println(node)
 }
+
+func TestTreeNodeParams(t *testing.T) {
+	root := &treeNode{}
+	root.Put("/user/get/:id")
+	root.Put("/user/:uid/order/:oid")
+	root.Put("/goods/*/info")
+	root.Put("/static/**")
+	root.Put("/files/**filepath")
+
+	tests := []struct {
+		path       string
+		routerName string
+		params     Params
+	}{
+		{"/user/get/1", "/user/get/:id", Params{{"id", "1"}}},
+		{"/user/10/order/20", "/user/:uid/order/:oid", Params{{"uid", "10"}, {"oid", "20"}}},
+		{"/goods/phone/info", "/goods/*/info", Params{{"*", "phone"}}},
+		{"/static/css/app.css", "/static/**", Params{{"**", "css/app.css"}}},
+		{"/files/a/b.txt", "/files/**filepath", Params{{"filepath", "a/b.txt"}}},
+	}
+	for _, tt := range tests {
+		var params Params
+		node := root.Get(tt.path, &params)
+		if node == nil || !node.isEnd {
+			t.Fatalf("%s: not found", tt.path)
+		}
+		if node.routerName != tt.routerName {
+			t.Errorf("%s: routerName = %s, want %s", tt.path, node.routerName, tt.routerName)
+		}
+		if fmt.Sprint(params) != fmt.Sprint(tt.params) {
+			t.Errorf("%s: params = %v, want %v", tt.path, params, tt.params)
+		}
+	}
+
+	for _, path := range []string{"/user/get", "/user/get/1/2", "/goods/phone", "/order"} {
+		if node := root.Get(path, nil); node != nil && node.isEnd {
+			t.Errorf("%s: unexpected match %s", path, node.routerName)
+		}
+	}
+}
+
+func TestTreeNodePriority(t *testing.T) {
+	root := &treeNode{}
+	//注册顺序和优先级相反
+	root.Put("/user/**")
+	root.Put("/user/*")
+	root.Put("/user/:id")
+	root.Put("/user/me")
+	root.Put("/user/:id/info")
+
+	tests := []struct {
+		path       string
+		routerName string
+	}{
+		{"/user/me", "/user/me"},
+		{"/user/10", "/user/:id"},
+		{"/user/me/info", "/user/:id/info"},
+		{"/user/10/orders", "/user/**"},
+	}
+	for _, tt := range tests {
+		node := root.Get(tt.path, nil)
+		if node == nil || node.routerName != tt.routerName {
+			t.Errorf("%s: got %v, want %s", tt.path, node, tt.routerName)
+		}
+	}
+}
+
+func TestTreeNodeConflict(t *testing.T) {
+	root := &treeNode{}
+	if err := root.Put("/user/:id/info"); err != nil {
+		t.Fatal(err)
+	}
+	err := root.Put("/user/:name")
+	if err == nil {
+		t.Fatal("expected conflict between /user/:name and /user/:id/info")
+	}
+	if !strings.Contains(err.Error(), "/user/:name") || !strings.Contains(err.Error(), "/user/:id/info") {
+		t.Errorf("error should list both routes: %v", err)
+	}
+	if err := root.Put("/files/**/info"); err == nil {
+		t.Error("expected error for ** not at the end")
+	}
+
+	g := New().Group("user")
+	h := func(ctx *Context) {}
+	if err := g.Handle(http.MethodGet, "/get/:id", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Handle(http.MethodPost, "/get/:id", h); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Handle(http.MethodGet, "/get/:id", h); err == nil {
+		t.Error("expected duplicate route error")
+	}
+	if err := g.Handle(http.MethodGet, "/get/:uid", h); err == nil {
+		t.Error("expected ambiguous route error")
+	}
+}
@@ -0,0 +1,417 @@
+package msgo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrBodyTooLarge = errors.New("request body too large")
+	ErrFileTooLarge = errors.New("upload file too large")
+	ErrFileType     = errors.New("upload file type not allowed")
+	ErrNoStorage    = errors.New("upload storage is not configured")
+)
+
+// UploadPolicy 路由的上传限制 使用 Upload 中间件设置
+type UploadPolicy struct {
+	//整个请求体的最大字节数 超过时返回 413 0 表示不限制
+	MaxBodySize int64
+	//单个文件的最大字节数 0 表示不限制
+	MaxFileSize int64
+	//解析 multipart 时保存在内存中的大小 超过的部分写入临时文件 默认 32M
+	MaxMemory int64
+	//允许的类型 根据文件内容识别 不信任客户端的 Content-Type 如 image/png image/* 为空时不限制
+	AllowedTypes []string
+	//SaveFile 和 StreamUpload 使用的存储
+	Storage Storage
+}
+
+// Storage 上传文件的存储 name 已经过 SafeFilename 处理 返回实际保存的名字
+type Storage interface {
+	Save(ctx context.Context, name string, r io.Reader) (string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// UploadedFile 保存成功的文件
+type UploadedFile struct {
+	Field       string
+	Filename    string
+	Name        string
+	Size        int64
+	ContentType string
+}
+
+// Upload 为路由设置上传限制 Content-Length 超过 MaxBodySize 时直接返回 413
+func Upload(policy UploadPolicy) MiddlewareFunc {
+	if policy.MaxMemory <= 0 {
+		policy.MaxMemory = defaultMultipartMemory
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx *Context) {
+			if !limitBody(ctx, policy.MaxBodySize) {
+				return
+			}
+			ctx.uploadPolicy = &policy
+			next(ctx)
+		}
+	}
+}
+
+func (c *Context) maxMemory() int64 {
+	if c.uploadPolicy != nil {
+		return c.uploadPolicy.MaxMemory
+	}
+	return defaultMultipartMemory
+}
+
+// checkFile 检查大小和内容的类型
+func (c *Context) checkFile(file *multipart.FileHeader) error {
+	policy := c.uploadPolicy
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxFileSize > 0 && file.Size > policy.MaxFileSize {
+		return ErrFileTooLarge
+	}
+	if len(policy.AllowedTypes) == 0 {
+		return nil
+	}
+	f, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, _, err = sniff(f, policy)
+	return err
+}
+
+func allowedType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		if a == mediaType || (strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, a[:len(a)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveFile 把通过检查的文件保存到 UploadPolicy.Storage 文件名经过 SafeFilename 处理
+func (c *Context) SaveFile(file *multipart.FileHeader) (*UploadedFile, error) {
+	policy := c.uploadPolicy
+	if policy == nil || policy.Storage == nil {
+		return nil, ErrNoStorage
+	}
+	if policy.MaxFileSize > 0 && file.Size > policy.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	br, contentType, err := sniff(src, policy)
+	if err != nil {
+		return nil, err
+	}
+	filename := SafeFilename(file.Filename)
+	name, err := policy.Storage.Save(c.R.Context(), filename, br)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadedFile{
+		Field:       c.formField(file),
+		Filename:    filename,
+		Name:        name,
+		Size:        file.Size,
+		ContentType: contentType,
+	}, nil
+}
+
+// formField 在 MultipartForm 中查找文件所属的字段
+func (c *Context) formField(file *multipart.FileHeader) string {
+	if c.R.MultipartForm == nil {
+		return ""
+	}
+	for field, files := range c.R.MultipartForm.File {
+		for _, f := range files {
+			if f == file {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+// sniff 根据前 512 个字节识别类型 返回的 reader 仍然从头开始读
+func sniff(r io.Reader, policy *UploadPolicy) (*bufio.Reader, string, error) {
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+	contentType := http.DetectContentType(head)
+	if len(policy.AllowedTypes) > 0 && !allowedType(contentType, policy.AllowedTypes) {
+		return nil, "", ErrFileType
+	}
+	return br, contentType, nil
+}
+
+// StreamUpload 不经过 ParseMultipartForm 边读边写入 UploadPolicy.Storage
+// 普通字段放入 c.R.PostForm 超过 MaxMemory 时返回 ErrBodyTooLarge 任意一个文件失败时删除已经保存的文件并返回错误
+func (c *Context) StreamUpload() ([]*UploadedFile, error) {
+	if c.uploadPolicy == nil || c.uploadPolicy.Storage == nil {
+		return nil, ErrNoStorage
+	}
+	policy := c.uploadPolicy
+	reader, err := c.R.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	if c.R.PostForm == nil {
+		c.R.PostForm = make(map[string][]string)
+	}
+	files := make([]*UploadedFile, 0)
+	cleanup := func() {
+		for _, f := range files {
+			_ = policy.Storage.Delete(c.R.Context(), f.Name)
+		}
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			cleanup()
+			return nil, c.bodyError(err)
+		}
+		if part.FileName() == "" {
+			//多读一个字节 超过 MaxMemory 时返回错误 不截断
+			value, err := io.ReadAll(io.LimitReader(part, c.maxMemory()+1))
+			if err != nil {
+				cleanup()
+				return nil, c.bodyError(err)
+			}
+			if int64(len(value)) > c.maxMemory() {
+				cleanup()
+				return nil, fmt.Errorf("%w: form field %s exceeds %d bytes", ErrBodyTooLarge, part.FormName(), c.maxMemory())
+			}
+			c.R.PostForm.Add(part.FormName(), string(value))
+			continue
+		}
+		file, err := c.savePart(part, policy)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		files = append(files, file)
+	}
+}
+
+func (c *Context) savePart(part *multipart.Part, policy *UploadPolicy) (*UploadedFile, error) {
+	br, contentType, err := sniff(part, policy)
+	if err != nil {
+		return nil, c.bodyError(err)
+	}
+	counter := &countReader{r: br}
+	if policy.MaxFileSize > 0 {
+		counter.r = newLimitedReader(io.NopCloser(br), policy.MaxFileSize, ErrFileTooLarge)
+	}
+	filename := SafeFilename(part.FileName())
+	name, err := policy.Storage.Save(c.R.Context(), filename, counter)
+	if err != nil {
+		return nil, c.bodyError(err, counter.r)
+	}
+	return &UploadedFile{
+		Field:       part.FormName(),
+		Filename:    filename,
+		Name:        name,
+		Size:        counter.n,
+		ContentType: contentType,
+	}, nil
+}
+
+// bodyError 读取超过限制时 multipart 或者 Storage 返回的错误可能没有包装原来的错误
+// 检查请求体和 readers 是否超过了限制 包装成可以用 errors.Is 判断的错误
+func (c *Context) bodyError(err error, readers ...io.Reader) error {
+	if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrFileTooLarge) {
+		return err
+	}
+	for _, r := range append(readers, c.R.Body) {
+		if limit := exceededLimit(r); limit != nil {
+			return fmt.Errorf("%w: %v", limit, err)
+		}
+	}
+	return err
+}
+
+// SafeFilename 去掉客户端传来的目录 只保留文件名 ../../etc/passwd -> passwd
+// 控制字符和路径分隔符等替换为 _ 开头的 . 去掉 避免生成隐藏文件
+func SafeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name = strings.TrimLeft(b.String(), ".")
+	if len(name) > 200 {
+		ext := filepath.Ext(name)
+		if len(ext) > 20 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:200-len(ext)], "") + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// DiskStorage 保存到本地目录 同名文件存在时加上序号 不会覆盖
+type DiskStorage struct {
+	Dir string
+	//文件权限 默认 0644
+	Perm os.FileMode
+}
+
+func (s *DiskStorage) Save(ctx context.Context, name string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", err
+	}
+	perm := s.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; i < 1000; i++ {
+		candidate := name
+		if i > 0 {
+			candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		path, err := s.path(candidate)
+		if err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(f, r)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			//不保留写了一半的文件
+			_ = os.Remove(path)
+			return "", err
+		}
+		return candidate, nil
+	}
+	return "", fmt.Errorf("upload: too many files named %s", name)
+}
+
+func (s *DiskStorage) Delete(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// path 最终的路径必须在 Dir 之内
+func (s *DiskStorage) path(name string) (string, error) {
+	dir, err := filepath.Abs(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == "." || strings.HasPrefix(rel, "..") || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("upload: invalid file name %q", name)
+	}
+	return path, nil
+}
+
+// maxBytesReader 超过 n 字节时返回 ErrBodyTooLarge
+func maxBytesReader(r io.ReadCloser, n int64) io.ReadCloser {
+	return newLimitedReader(r, n, ErrBodyTooLarge)
+}
+
+type limitedReader struct {
+	r   io.ReadCloser
+	n   int64
+	err error
+}
+
+func newLimitedReader(r io.ReadCloser, n int64, err error) *limitedReader {
+	return &limitedReader{r: r, n: n, err: err}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, l.err
+	}
+	//多读一个字节 才能区分刚好等于限制和超过限制
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n - int(-l.n), l.err
+	}
+	return n, err
+}
+
+// exceededLimit 超过限制时返回对应的错误 多层限制时逐层检查
+func exceededLimit(r io.Reader) error {
+	for {
+		l, ok := r.(*limitedReader)
+		if !ok {
+			return nil
+		}
+		if l.n < 0 {
+			return l.err
+		}
+		r = l.r
+	}
+}
+
+func (l *limitedReader) Close() error {
+	return l.r.Close()
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
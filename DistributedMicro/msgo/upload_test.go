@@ -0,0 +1,170 @@
+package msgo
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+func multipartRequest(t *testing.T, path string, files map[string][]byte) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("name", "msgo")
+	for filename, content := range files {
+		part, err := w.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	w.Close()
+	r := httptest.NewRequest(http.MethodPost, path, &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestSafeFilename(t *testing.T) {
+	tests := map[string]string{
+		"../../etc/passwd":    "passwd",
+		`..\..\windows\a.ini`: "a.ini",
+		"..":                  "file",
+		"":                    "file",
+		".bashrc":             "bashrc",
+		"a b<c>.png":          "a_b_c_.png",
+		"头像.jpg":              "头像.jpg",
+		"x\x00.txt":           "x_.txt",
+	}
+	for name, want := range tests {
+		if got := SafeFilename(name); got != want {
+			t.Errorf("SafeFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestUploadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	var saved []*UploadedFile
+	var uploadErr error
+	policy := UploadPolicy{
+		MaxBodySize:  1 << 10,
+		MaxFileSize:  100,
+		AllowedTypes: []string{"image/*"},
+		Storage:      &DiskStorage{Dir: dir},
+	}
+	g := engine.Group("upload")
+	g.Post("/form", func(ctx *Context) {
+		saved, uploadErr = nil, nil
+		files, err := ctx.FormFiles("file")
+		if err != nil {
+			uploadErr = err
+			return
+		}
+		for _, file := range files {
+			f, err := ctx.SaveFile(file)
+			if err != nil {
+				uploadErr = err
+				return
+			}
+			saved = append(saved, f)
+		}
+	}, Upload(policy))
+	g.Post("/stream", func(ctx *Context) {
+		saved, uploadErr = ctx.StreamUpload()
+	}, Upload(policy))
+	g.Post("/missing", func(ctx *Context) {
+		//没有文件时返回错误 不会 panic
+		_, uploadErr = ctx.FormFile("file")
+	})
+
+	for _, path := range []string{"/upload/form", "/upload/stream"} {
+		engine.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, path, map[string][]byte{"../../evil.png": pngHeader}))
+		if uploadErr != nil || len(saved) != 1 || saved[0].ContentType != "image/png" || saved[0].Field != "file" {
+			t.Fatalf("%s: saved = %v, err = %v", path, saved, uploadErr)
+		}
+		if _, err := os.Stat(filepath.Join(dir, saved[0].Name)); err != nil || filepath.Dir(saved[0].Name) != "." {
+			t.Errorf("%s: saved as %q, err = %v", path, saved[0].Name, err)
+		}
+
+		engine.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, path, map[string][]byte{"a.png": []byte("<html>not an image</html>")}))
+		if !errors.Is(uploadErr, ErrFileType) {
+			t.Errorf("%s: content type err = %v", path, uploadErr)
+		}
+		engine.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, path, map[string][]byte{"big.png": append(pngHeader, make([]byte, 200)...)}))
+		if !errors.Is(uploadErr, ErrFileTooLarge) {
+			t.Errorf("%s: file size err = %v", path, uploadErr)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != "evil-1.png" || entries[1].Name() != "evil.png" {
+		t.Errorf("saved files = %v", entries)
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, multipartRequest(t, "/upload/form", map[string][]byte{"a.png": make([]byte, 2<<10)}))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("body size code = %d", w.Code)
+	}
+
+	engine.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, "/upload/missing", nil))
+	if !errors.Is(uploadErr, http.ErrMissingFile) {
+		t.Errorf("missing file err = %v", uploadErr)
+	}
+}
+
+func TestUploadBindBodyLimit(t *testing.T) {
+	engine := New()
+	var bindErr error
+	g := engine.Group("upload")
+	g.Post("/bind", func(ctx *Context) {
+		var form struct {
+			Name string `form:"name"`
+		}
+		bindErr = ctx.Bind(&form)
+	}, Upload(UploadPolicy{MaxBodySize: 100, MaxMemory: 1 << 10}))
+
+	r := multipartRequest(t, "/upload/bind", map[string][]byte{"a.png": make([]byte, 200)})
+	//没有 Content-Length 时读取过程中才发现超过限制
+	r.ContentLength = -1
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if !errors.Is(bindErr, ErrBodyTooLarge) || w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("bind err = %v, code = %d", bindErr, w.Code)
+	}
+}
+
+func TestStreamUploadFieldLimit(t *testing.T) {
+	engine := New()
+	var uploadErr error
+	engine.Group("upload").Post("/stream", func(ctx *Context) {
+		_, uploadErr = ctx.StreamUpload()
+		if errors.Is(uploadErr, ErrBodyTooLarge) {
+			ctx.AbortWithStatus(http.StatusRequestEntityTooLarge)
+		}
+	}, Upload(UploadPolicy{MaxMemory: 4, Storage: &DiskStorage{Dir: t.TempDir()}}))
+
+	//name 字段是 msgo 刚好 4 个字节
+	engine.ServeHTTP(httptest.NewRecorder(), multipartRequest(t, "/upload/stream", nil))
+	if uploadErr != nil {
+		t.Fatalf("field within limit: err = %v", uploadErr)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "msgo-too-long")
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/upload/stream", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, r)
+	if !errors.Is(uploadErr, ErrBodyTooLarge) || w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized field: err = %v, code = %d", uploadErr, w.Code)
+	}
+}
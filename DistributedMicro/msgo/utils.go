@@ -1,6 +1,10 @@
 package msgo
 
 import (
+	"net"
+	"path"
+	"reflect"
+	"runtime"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -14,6 +18,35 @@ func SubStringLast(str string, substr string) string {
 	return str[index+len(substr):]
 }
 
+// joinPaths /api + v1 -> /api/v1
+func joinPaths(prefix string, name string) string {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		return prefix
+	}
+	return prefix + "/" + name
+}
+
+// localIP 第一个非回环的 IPv4 地址 找不到时返回 127.0.0.1
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+// nameOfFunction github.com/mszlu521/msgo.Logging -> msgo.Logging
+func nameOfFunction(f any) string {
+	return path.Base(runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name())
+}
+
 func isASCII(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] > unicode.MaxASCII {
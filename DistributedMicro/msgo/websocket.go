@@ -0,0 +1,27 @@
+package msgo
+
+import (
+	"github.com/mszlu521/msgo/websocket"
+	"net/http"
+)
+
+// WebSocketHandler 升级成功后调用 返回后连接会被关闭
+type WebSocketHandler func(ctx *Context, conn *websocket.Conn)
+
+// WebSocket 注册 GET 路由 中间件(鉴权 限流等)在升级之前执行 被中止时按普通的 http 请求返回
+// 连接的超时 心跳和发送队列使用 Engine.WebSocketOptions
+func (r *routerGroup) WebSocket(name string, handler WebSocketHandler, middlewares ...MiddlewareFunc) {
+	r.Get(name, func(ctx *Context) {
+		conn, err := websocket.Upgrade(ctx.W, ctx.R, &ctx.engine.WebSocketOptions)
+		if err != nil {
+			if ctx.Logger != nil {
+				ctx.Logger.Error(err)
+			}
+			return
+		}
+		defer conn.Close()
+		ctx.writer.status = http.StatusSwitchingProtocols
+		ctx.StatusCode = http.StatusSwitchingProtocols
+		handler(ctx, conn)
+	}, middlewares...)
+}
@@ -0,0 +1,399 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 消息类型 和帧的 opcode 一致
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭码 RFC 6455 7.4.1
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseMessageTooBig    = 1009
+)
+
+var (
+	ErrSendQueueFull = errors.New("websocket: send queue is full")
+	ErrClosed        = errors.New("websocket: connection closed")
+	ErrReadLimit     = errors.New("websocket: message too large")
+	errProtocol      = errors.New("websocket: protocol error")
+)
+
+// CloseError 对方发送了关闭帧
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// Options 连接的超时 心跳和队列大小 零值使用默认值
+type Options struct {
+	//等待对方数据的最长时间 收到任何帧(包括 pong)都会重新计时 默认 60s
+	ReadTimeout time.Duration
+	//每次写的超时时间 默认 10s
+	WriteTimeout time.Duration
+	//发送 ping 的间隔 默认 ReadTimeout 的 9/10 小于 0 时不发送
+	PingInterval time.Duration
+	//发送队列的长度 队列满时 WriteMessage 返回 ErrSendQueueFull 默认 256
+	SendQueueSize int
+	//单条消息的最大字节数 默认 1MB
+	MaxMessageSize int64
+	//服务端支持的子协议 按客户端的顺序选择第一个支持的
+	Subprotocols []string
+	//检查 Origin 默认要求和 Host 相同 没有 Origin 的请求(非浏览器)直接通过
+	CheckOrigin func(r *http.Request) bool
+}
+
+func (o *Options) withDefaults() Options {
+	var opts Options
+	if o != nil {
+		opts = *o
+	}
+	if opts.ReadTimeout == 0 {
+		opts.ReadTimeout = 60 * time.Second
+	}
+	if opts.WriteTimeout == 0 {
+		opts.WriteTimeout = 10 * time.Second
+	}
+	if opts.PingInterval == 0 {
+		opts.PingInterval = opts.ReadTimeout * 9 / 10
+	}
+	if opts.SendQueueSize <= 0 {
+		opts.SendQueueSize = 256
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = 1 << 20
+	}
+	return opts
+}
+
+// Conn 一个 websocket 连接
+// ReadMessage 只能在一个协程中调用 WriteMessage 可以并发调用
+// 所有的写都放入队列 由单独的协程写出 并定时发送 ping
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isClient    bool
+	opts        Options
+	subprotocol string
+
+	send       chan []byte
+	control    chan []byte
+	closing    chan struct{}
+	closeOnce  sync.Once
+	closeFrame []byte
+	writerDone chan struct{}
+
+	readErr error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isClient bool, opts Options, subprotocol string) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:        conn,
+		br:          br,
+		isClient:    isClient,
+		opts:        opts,
+		subprotocol: subprotocol,
+		send:        make(chan []byte, opts.SendQueueSize),
+		control:     make(chan []byte, 16),
+		closing:     make(chan struct{}),
+		writerDone:  make(chan struct{}),
+	}
+	go c.writeLoop()
+	return c
+}
+
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// WriteMessage 放入发送队列 不等待写出 队列满时返回 ErrSendQueueFull
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: unsupported message type %d", messageType)
+	}
+	select {
+	case <-c.closing:
+		return ErrClosed
+	default:
+	}
+	frame := encodeFrame(messageType, data, c.isClient)
+	select {
+	case c.send <- frame:
+		return nil
+	case <-c.writerDone:
+		return ErrClosed
+	default:
+		return ErrSendQueueFull
+	}
+}
+
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, data)
+}
+
+// ReadMessage 读取一条完整的消息 自动回复 ping 处理分片
+// 对方关闭时返回 *CloseError 之后的调用返回同样的错误
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, data, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return
+}
+
+func (c *Conn) ReadJSON(v any) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	var messageType int
+	var message []byte
+	for {
+		if err := setDeadline(c.conn.SetReadDeadline, c.opts.ReadTimeout); err != nil {
+			return 0, nil, err
+		}
+		fin, opcode, payload, err := c.readFrame(c.opts.MaxMessageSize - int64(len(message)))
+		if err != nil {
+			switch err {
+			case ErrReadLimit:
+				_ = c.CloseWithMessage(CloseMessageTooBig, "")
+			case errProtocol:
+				_ = c.CloseWithMessage(CloseProtocolError, "")
+			}
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			c.writeControl(PongMessage, payload)
+			continue
+		case PongMessage:
+			//读超时已经在读取下一帧前重新计时
+			continue
+		case CloseMessage:
+			closeErr := parseClose(payload)
+			_ = c.CloseWithMessage(closeErr.Code, "")
+			return 0, nil, closeErr
+		case continuationFrame:
+			if messageType == 0 {
+				_ = c.CloseWithMessage(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+		default:
+			if messageType != 0 {
+				_ = c.CloseWithMessage(CloseProtocolError, "")
+				return 0, nil, errProtocol
+			}
+			messageType = opcode
+		}
+		message = append(message, payload...)
+		if fin {
+			return messageType, message, nil
+		}
+	}
+}
+
+// readFrame 读取一帧 limit 是允许的最大负载
+func (c *Conn) readFrame(limit int64) (fin bool, opcode int, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+	length := int64(header[1] & 0x7f)
+	//没有协商扩展 rsv 必须为 0 服务端收到的帧必须有掩码 客户端收到的不能有
+	if header[0]&0x70 != 0 || masked == c.isClient {
+		return false, 0, nil, errProtocol
+	}
+	switch opcode {
+	case continuationFrame, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !fin || length > 125 {
+			return false, 0, nil, errProtocol
+		}
+	default:
+		return false, 0, nil, errProtocol
+	}
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			return false, 0, nil, errProtocol
+		}
+	}
+	if opcode < CloseMessage && length > limit {
+		return false, 0, nil, ErrReadLimit
+	}
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return
+}
+
+func parseClose(payload []byte) *CloseError {
+	if len(payload) < 2 {
+		return &CloseError{Code: CloseNoStatusReceived}
+	}
+	return &CloseError{Code: int(binary.BigEndian.Uint16(payload)), Text: string(payload[2:])}
+}
+
+// writeControl ping pong 优先于队列中的消息发送 控制队列满时丢弃
+func (c *Conn) writeControl(opcode int, payload []byte) {
+	select {
+	case c.control <- encodeFrame(opcode, payload, c.isClient):
+	default:
+	}
+}
+
+// Close 发送队列中剩余的消息和关闭帧后关闭连接
+func (c *Conn) Close() error {
+	return c.CloseWithMessage(CloseNormalClosure, "")
+}
+
+func (c *Conn) CloseWithMessage(code int, text string) error {
+	c.closeOnce.Do(func() {
+		var payload []byte
+		if code != CloseNoStatusReceived {
+			payload = make([]byte, 2, 2+len(text))
+			binary.BigEndian.PutUint16(payload, uint16(code))
+			payload = append(payload, text...)
+		}
+		c.closeFrame = encodeFrame(CloseMessage, payload, c.isClient)
+		close(c.closing)
+	})
+	<-c.writerDone
+	return nil
+}
+
+func (c *Conn) writeLoop() {
+	defer close(c.writerDone)
+	defer c.conn.Close()
+	var ping <-chan time.Time
+	if c.opts.PingInterval > 0 {
+		ticker := time.NewTicker(c.opts.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		var frame []byte
+		select {
+		case frame = <-c.control:
+		default:
+			select {
+			case frame = <-c.control:
+			case frame = <-c.send:
+			case <-ping:
+				frame = encodeFrame(PingMessage, nil, c.isClient)
+			case <-c.closing:
+				c.drain()
+				return
+			}
+		}
+		if c.write(frame) != nil {
+			return
+		}
+	}
+}
+
+// drain 关闭前写出队列中的消息 整个过程共用一个写超时
+func (c *Conn) drain() {
+	if err := setDeadline(c.conn.SetWriteDeadline, c.opts.WriteTimeout); err != nil {
+		return
+	}
+	for {
+		select {
+		case frame := <-c.send:
+			if _, err := c.conn.Write(frame); err != nil {
+				return
+			}
+		default:
+			_, _ = c.conn.Write(c.closeFrame)
+			return
+		}
+	}
+}
+
+func (c *Conn) write(frame []byte) error {
+	if err := setDeadline(c.conn.SetWriteDeadline, c.opts.WriteTimeout); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// setDeadline 每次都重新设置 覆盖 http.Server 在劫持前设置的超时
+func setDeadline(set func(time.Time) error, timeout time.Duration) error {
+	if timeout <= 0 {
+		return set(time.Time{})
+	}
+	return set(time.Now().Add(timeout))
+}
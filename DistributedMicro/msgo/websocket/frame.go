@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+)
+
+// encodeFrame 编码一个完整的帧 发送时不分片 客户端发送的帧需要掩码
+func encodeFrame(opcode int, payload []byte, mask bool) []byte {
+	length := len(payload)
+	frame := make([]byte, 0, 14+length)
+	frame = append(frame, 0x80|byte(opcode))
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch {
+	case length <= 125:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[len(frame)-2:], uint16(length))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(length))
+	}
+	if !mask {
+		return append(frame, payload...)
+	}
+	var key [4]byte
+	_, _ = rand.Read(key[:])
+	frame = append(frame, key[:]...)
+	start := len(frame)
+	frame = append(frame, payload...)
+	maskBytes(key, frame[start:])
+	return frame
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
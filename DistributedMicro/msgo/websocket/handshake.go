@@ -0,0 +1,232 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// HandshakeError 升级失败 错误响应已经写给客户端
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string {
+	return "websocket: " + e.Message
+}
+
+// Upgrade 把 http 连接升级为 websocket 失败时写出错误响应并返回 *HandshakeError
+func Upgrade(w http.ResponseWriter, r *http.Request, opts *Options) (*Conn, error) {
+	o := opts.withDefaults()
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'upgrade' token not found in 'Connection' header")
+	}
+	if !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, handshakeError(w, http.StatusBadRequest, "'Sec-WebSocket-Key' header is missing")
+	}
+	checkOrigin := o.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, handshakeError(w, http.StatusForbidden, "request origin not allowed")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "response does not implement http.Hijacker")
+	}
+	subprotocol := selectSubprotocol(r, o.Subprotocols)
+	netConn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+	if err := setDeadline(netConn.SetWriteDeadline, o.WriteTimeout); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	//劫持时 bufio.Reader 中可能已经有客户端发来的帧
+	return newConn(netConn, brw.Reader, false, o, subprotocol), nil
+}
+
+func handshakeError(w http.ResponseWriter, status int, message string) error {
+	err := &HandshakeError{Status: status, Message: message}
+	http.Error(w, err.Error(), status)
+	return err
+}
+
+// Dial 连接 websocket 服务 ws:// 或 wss:// header 中可以携带鉴权信息
+// 握手失败时返回 ErrBadHandshake 和服务端的响应
+func Dial(ctx context.Context, rawURL string, header http.Header, opts *Options) (*Conn, *http.Response, error) {
+	o := opts.withDefaults()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	default:
+		return nil, nil, fmt.Errorf("websocket: bad scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme == "https" {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			netConn.Close()
+			return nil, nil, err
+		}
+		netConn = tlsConn
+	}
+	conn, resp, err := clientHandshake(ctx, netConn, u, header, o)
+	if err != nil {
+		netConn.Close()
+		return nil, resp, err
+	}
+	return conn, resp, nil
+}
+
+func clientHandshake(ctx context.Context, netConn net.Conn, u *url.URL, header http.Header, o Options) (*Conn, *http.Response, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = netConn.SetDeadline(deadline)
+	} else {
+		_ = netConn.SetDeadline(time.Now().Add(o.WriteTimeout))
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if len(o.Subprotocols) > 0 {
+		req.Header.Set("Sec-WebSocket-Protocol", strings.Join(o.Subprotocols, ", "))
+	}
+	if err := req.Write(netConn); err != nil {
+		return nil, nil, err
+	}
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContains(resp.Header, "Upgrade", "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		//连接会被关闭 先读出响应体
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return nil, resp, ErrBadHandshake
+	}
+	resp.Body = http.NoBody
+	_ = netConn.SetDeadline(time.Time{})
+	return newConn(netConn, br, true, o, resp.Header.Get("Sec-WebSocket-Protocol")), resp, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains 头中逗号分隔的值是否包含 token 忽略大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, protocol := range strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		protocol = strings.TrimSpace(protocol)
+		for _, s := range supported {
+			if protocol == s {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
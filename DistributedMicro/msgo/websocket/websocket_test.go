@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T, opts *Options, handler func(conn *Conn)) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, opts)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string, opts *Options) *Conn {
+	conn, _, err := Dial(context.Background(), url, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestEcho(t *testing.T) {
+	url := newServer(t, &Options{Subprotocols: []string{"chat"}}, func(conn *Conn) {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(messageType, data)
+		}
+	})
+	conn := dial(t, url, &Options{Subprotocols: []string{"v2", "chat"}})
+	if conn.Subprotocol() != "chat" {
+		t.Errorf("subprotocol = %q", conn.Subprotocol())
+	}
+	messages := [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), 300), bytes.Repeat([]byte("b"), 70000)}
+	for i, message := range messages {
+		messageType := TextMessage
+		if i == 2 {
+			messageType = BinaryMessage
+		}
+		if err := conn.WriteMessage(messageType, message); err != nil {
+			t.Fatal(err)
+		}
+		gotType, got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotType != messageType || !bytes.Equal(got, message) {
+			t.Errorf("message %d: type = %d, len = %d", i, gotType, len(got))
+		}
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	url := newServer(t, nil, func(conn *Conn) {})
+	resp, err := http.Get("http" + strings.TrimPrefix(url, "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plain GET code = %d", resp.StatusCode)
+	}
+	header := http.Header{"Origin": {"http://evil.example.com"}}
+	_, resp, err = Dial(context.Background(), url, header, nil)
+	if !errors.Is(err, ErrBadHandshake) || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross origin: err = %v, resp = %v", err, resp)
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	serverErr := make(chan error, 1)
+	opts := &Options{ReadTimeout: 150 * time.Millisecond, PingInterval: 30 * time.Millisecond}
+	url := newServer(t, opts, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		serverErr <- err
+	})
+
+	//客户端在读 会自动回复 pong 服务端不会超时
+	conn := dial(t, url, &Options{PingInterval: -1})
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-serverErr:
+		t.Fatalf("server read returned early: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	_ = conn.WriteMessage(TextMessage, []byte("done"))
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+
+	//客户端不读 没有 pong 服务端读超时
+	_ = dial(t, url, &Options{PingInterval: -1})
+	select {
+	case err := <-serverErr:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("err = %v, want timeout", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server read did not time out")
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	result := make(chan error, 1)
+	opts := &Options{SendQueueSize: 2, WriteTimeout: 200 * time.Millisecond, PingInterval: -1}
+	url := newServer(t, opts, func(conn *Conn) {
+		message := bytes.Repeat([]byte("x"), 1<<16)
+		for i := 0; i < 10000; i++ {
+			if err := conn.WriteMessage(BinaryMessage, message); err != nil {
+				result <- err
+				return
+			}
+		}
+		result <- nil
+	})
+	//客户端不读 socket 缓冲区写满后队列也会满
+	_ = dial(t, url, nil)
+	if err := <-result; err != ErrSendQueueFull {
+		t.Errorf("err = %v, want ErrSendQueueFull", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	serverErr := make(chan error, 1)
+	url := newServer(t, &Options{MaxMessageSize: 10}, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		serverErr <- err
+	})
+
+	conn := dial(t, url, nil)
+	_ = conn.CloseWithMessage(CloseGoingAway, "bye")
+	var closeErr *CloseError
+	if err := <-serverErr; !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Text != "bye" {
+		t.Errorf("err = %v", err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("x")); err != ErrClosed {
+		t.Errorf("write after close = %v", err)
+	}
+
+	conn = dial(t, url, nil)
+	_ = conn.WriteMessage(TextMessage, []byte("more than ten bytes"))
+	if err := <-serverErr; err != ErrReadLimit {
+		t.Errorf("server err = %v, want ErrReadLimit", err)
+	}
+	if _, _, err := conn.ReadMessage(); !errors.As(err, &closeErr) || closeErr.Code != CloseMessageTooBig {
+		t.Errorf("client err = %v", err)
+	}
+}
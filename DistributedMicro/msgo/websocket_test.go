@@ -0,0 +1,57 @@
+package msgo_test
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/mszlu521/msgo"
+	"github.com/mszlu521/msgo/token"
+	"github.com/mszlu521/msgo/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSocket(t *testing.T) {
+	engine := msgo.New()
+	jh := &token.JwtHandler{Key: []byte("123456")}
+	g := engine.Group("ws")
+	g.Use(jh.AuthInterceptor)
+	g.WebSocket("/order", func(ctx *msgo.Context, conn *websocket.Conn) {
+		claims, _ := ctx.Get("jwt_claims")
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(claims.(jwt.MapClaims)["name"].(string)+":"+string(data)))
+		_, _, _ = conn.ReadMessage()
+	}, msgo.Limiter(0, 1))
+	server := httptest.NewServer(engine)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/order"
+
+	//鉴权在升级之前执行
+	_, resp, err := websocket.Dial(context.Background(), url, nil, nil)
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("without token: err = %v, resp = %v", err, resp)
+	}
+
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"name": "msgo"}).SignedString(jh.Key)
+	header := http.Header{"Authorization": {signed}}
+	conn, _, err := websocket.Dial(context.Background(), url, header, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.WriteMessage(websocket.TextMessage, []byte("paid"))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "msgo:paid" {
+		t.Errorf("data = %q, err = %v", data, err)
+	}
+
+	//令牌桶只有一个令牌且不再补充 限流同样在升级之前返回
+	_, resp, err = websocket.Dial(context.Background(), url, header, nil)
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("limited: err = %v, resp = %v", err, resp)
+	}
+}